@@ -1,22 +1,51 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/ssm"
+	"sync"
+	"sync/atomic"
 )
 
 type Config struct {
-	systemManager *ssm.SSM
-	parameters    map[ConfigParameterName]string
+	sources     []ParameterSource
+	definitions []ConfigParameterDefinition
+	loaded      map[ConfigParameterName]bool
+	// parameters holds the current values. It's replaced as a whole when the config is refreshed so
+	// that readers never see a partially updated config.
+	parameters atomic.Pointer[parameterSnapshot]
+
+	subscribersMutex sync.Mutex
+	subscribers      map[ConfigParameterName][]Subscriber
+}
+
+// parameterSnapshot holds the values of the parameters and the source each was resolved from
+type parameterSnapshot struct {
+	values  map[ConfigParameterName]string
+	sources map[ConfigParameterName]string
+}
+
+func newParameterSnapshot() *parameterSnapshot {
+	return &parameterSnapshot{
+		values:  map[ConfigParameterName]string{},
+		sources: map[ConfigParameterName]string{},
+	}
 }
 
 type ConfigParameterDefinition struct {
 	ParameterName ConfigParameterName
 	ParameterType ConfigParameterType
+	// Required parameters must resolve to a non-empty value, either from a source or their default
+	Required bool
+	// RequiredUnlessSet, if set, names a parameter that lifts the Required check when it has a value,
+	// e.g. a local replacement for the resource the required parameter points to
+	RequiredUnlessSet ConfigParameterName
+	// Default is used when no source provides a value
+	Default string
+	// Validator, if set, checks non-empty values
+	Validator Validator
 }
 
 type ConfigParameterType string
@@ -29,88 +58,196 @@ const (
 
 type ConfigParameterName string
 
-func New(awsSession *session.Session) *Config {
-	return &Config{
-		systemManager: ssm.New(awsSession),
-		parameters:    map[ConfigParameterName]string{},
+// New creates a config that loads the parameters an execution mode needs. Standard and secret parameters
+// are resolved from the sources in precedence order: a parameter is taken from the first source that has it.
+// Unknown parameter names are a programming error and panic.
+func New(parameterNames []ConfigParameterName, sources ...ParameterSource) *Config {
+	definitions := []ConfigParameterDefinition{}
+	loaded := map[ConfigParameterName]bool{}
+	for _, name := range parameterNames {
+		definition, found := findDefinition(name)
+		if !found {
+			panic(fmt.Sprintf("config parameter %s is not defined in paramDefinitions", name))
+		}
+		if !loaded[name] {
+			definitions = append(definitions, definition)
+			loaded[name] = true
+		}
 	}
+	config := &Config{
+		sources:     sources,
+		definitions: definitions,
+		loaded:      loaded,
+		subscribers: map[ConfigParameterName][]Subscriber{},
+	}
+	config.parameters.Store(newParameterSnapshot())
+	return config
 }
 
-func (s *Config) retrieveStandardParameters() error {
-	paramNames := []*string{}
+// AllParameterNames returns the name of every defined parameter
+func AllParameterNames() []ConfigParameterName {
+	names := []ConfigParameterName{}
 	for _, definition := range paramDefinitions {
-		if definition.ParameterType == StandardParameter {
-			paramNames = append(paramNames, aws.String(string(definition.ParameterName)))
-		}
+		names = append(names, definition.ParameterName)
 	}
-	if len(paramNames) > 0 {
-		paramOutput, err := s.systemManager.GetParameters(&ssm.GetParametersInput{
-			Names:          paramNames,
-			WithDecryption: aws.Bool(false),
-		})
-		if err != nil {
-			return fmt.Errorf("error while retrieving standard SSM parameters => %v", err.Error())
-		}
-		for _, param := range paramOutput.Parameters {
-			if _, exists := s.parameters[ConfigParameterName(*param.Name)]; exists {
-				return fmt.Errorf("duplicate parameter name: %s", string(*param.Name))
-			}
-			s.parameters[ConfigParameterName(*param.Name)] = *param.Value
+	return names
+}
+
+func findDefinition(name ConfigParameterName) (ConfigParameterDefinition, bool) {
+	for _, definition := range paramDefinitions {
+		if definition.ParameterName == name {
+			return definition, true
 		}
 	}
-	return nil
+	return ConfigParameterDefinition{}, false
 }
 
-func (s *Config) retrieveSecretParameters() error {
-	paramNames := []*string{}
-	for _, definition := range paramDefinitions {
-		if definition.ParameterType == SecretParameter {
-			paramNames = append(paramNames, aws.String(string(definition.ParameterName)))
+// get returns the value of a parameter. Reading a parameter the execution mode didn't declare is a
+// programming error, so it panics rather than silently returning an empty value.
+func (s *Config) get(name ConfigParameterName) string {
+	if !s.loaded[name] {
+		panic(fmt.Sprintf("config parameter %s was read but isn't loaded in this execution mode", name))
+	}
+	return s.parameters.Load().values[name]
+}
+
+func (s *Config) retrieveSourceParameters(ctx context.Context, snapshot *parameterSnapshot) error {
+	remaining := []ConfigParameterDefinition{}
+	for _, definition := range s.definitions {
+		if definition.ParameterType == StandardParameter || definition.ParameterType == SecretParameter {
+			remaining = append(remaining, definition)
 		}
 	}
-	if len(paramNames) > 0 {
-		paramOutput, err := s.systemManager.GetParameters(&ssm.GetParametersInput{
-			Names:          paramNames,
-			WithDecryption: aws.Bool(true),
-		})
+	for _, source := range s.sources {
+		if len(remaining) == 0 {
+			break
+		}
+		parameters, err := source.GetParameters(ctx, remaining)
 		if err != nil {
-			return fmt.Errorf("error while retrieving secret SSM parameters => %v", err.Error())
+			return fmt.Errorf("error while retrieving parameters from %s source => %v", source.Name(), err.Error())
 		}
-		for _, param := range paramOutput.Parameters {
-			if _, exists := s.parameters[ConfigParameterName(*param.Name)]; exists {
-				return fmt.Errorf("duplicate parameter name: %s", string(*param.Name))
+		unresolved := []ConfigParameterDefinition{}
+		for _, definition := range remaining {
+			if value, ok := parameters[definition.ParameterName]; ok {
+				snapshot.values[definition.ParameterName] = value
+				snapshot.sources[definition.ParameterName] = source.Name()
+			} else {
+				unresolved = append(unresolved, definition)
 			}
-			s.parameters[ConfigParameterName(*param.Name)] = *param.Value
 		}
+		remaining = unresolved
 	}
 	return nil
 }
 
-func (s *Config) retrieveEnvironmentParameters() error {
-	for _, definition := range paramDefinitions {
+func (s *Config) retrieveEnvironmentParameters(snapshot *parameterSnapshot) error {
+	for _, definition := range s.definitions {
 		if definition.ParameterType == EnvironmentParameter {
-			if _, exists := s.parameters[definition.ParameterName]; exists {
+			if _, exists := snapshot.values[definition.ParameterName]; exists {
 				return fmt.Errorf("duplicate parameter name: %s", string(definition.ParameterName))
 			}
-			s.parameters[definition.ParameterName] = os.Getenv(string(definition.ParameterName))
+			if value, ok := os.LookupEnv(string(definition.ParameterName)); ok {
+				snapshot.values[definition.ParameterName] = value
+				snapshot.sources[definition.ParameterName] = EnvSourceName
+			}
 		}
 	}
 	return nil
 }
 
-func (s *Config) initParameters() error {
-	if err := s.retrieveStandardParameters(); err != nil {
-		return err
+// validateParameters applies defaults, then checks every parameter and returns a single error
+// listing all the parameters that are missing or invalid
+func (s *Config) validateParameters(snapshot *parameterSnapshot) error {
+	problems := []error{}
+	for _, definition := range s.definitions {
+		if snapshot.values[definition.ParameterName] == "" && definition.Default != "" {
+			snapshot.values[definition.ParameterName] = definition.Default
+			snapshot.sources[definition.ParameterName] = DefaultSourceName
+		}
+		if err := validateParameter(definition, snapshot.values); err != nil {
+			problems = append(problems, err)
+		}
 	}
-	if err := s.retrieveSecretParameters(); err != nil {
-		return err
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(problems...))
+	}
+	return nil
+}
+
+func validateParameter(definition ConfigParameterDefinition, values map[ConfigParameterName]string) error {
+	value := values[definition.ParameterName]
+	if value == "" {
+		if definition.Required && (definition.RequiredUnlessSet == "" || values[definition.RequiredUnlessSet] == "") {
+			return fmt.Errorf("%w: %s", ErrMissingParameter, definition.ParameterName)
+		}
+		return nil
 	}
-	if err := s.retrieveEnvironmentParameters(); err != nil {
-		return err
+	if definition.Validator != nil {
+		if err := definition.Validator(value); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidParameter, definition.ParameterName, err)
+		}
 	}
 	return nil
 }
 
-func (s *Config) InitializeParameters() error {
-	return s.initParameters()
+// resolveParameters reads every loaded parameter from the sources and the environment. The snapshot
+// is returned along with validation errors so that an invalid config can still be described.
+func (s *Config) resolveParameters(ctx context.Context) (*parameterSnapshot, error) {
+	snapshot := newParameterSnapshot()
+	if err := s.retrieveSourceParameters(ctx, snapshot); err != nil {
+		return nil, err
+	}
+	if err := s.retrieveEnvironmentParameters(snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, s.validateParameters(snapshot)
+}
+
+func (s *Config) InitializeParameters(ctx context.Context) error {
+	snapshot, err := s.resolveParameters(ctx)
+	if snapshot != nil {
+		s.parameters.Store(snapshot)
+	}
+	return err
+}
+
+// redactedValue replaces the value of secret parameters in ParameterStatus
+const redactedValue = "[redacted]"
+
+// ParameterStatus describes how a loaded parameter was resolved
+type ParameterStatus struct {
+	Name ConfigParameterName
+	Type ConfigParameterType
+	// Source is the source the value came from, or empty when the parameter didn't resolve
+	Source   string
+	Resolved bool
+	// Value is redacted for secret parameters
+	Value string
+	// Problem is why the parameter is missing or invalid, if it is
+	Problem string
+}
+
+// Describe reports the status of every loaded parameter, in definition order. It can be called after
+// InitializeParameters failed validation to see which parameters are at fault.
+func (s *Config) Describe() []ParameterStatus {
+	snapshot := s.parameters.Load()
+	statuses := []ParameterStatus{}
+	for _, definition := range s.definitions {
+		value := snapshot.values[definition.ParameterName]
+		status := ParameterStatus{
+			Name:     definition.ParameterName,
+			Type:     definition.ParameterType,
+			Source:   snapshot.sources[definition.ParameterName],
+			Resolved: value != "",
+			Value:    value,
+		}
+		if definition.ParameterType == SecretParameter && value != "" {
+			status.Value = redactedValue
+		}
+		if err := validateParameter(definition, snapshot.values); err != nil {
+			status.Problem = err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
 }
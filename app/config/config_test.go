@@ -0,0 +1,106 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// validParameters returns a source with a valid value for every required parameter
+func validParameters() staticSource {
+	return staticSource{
+		PlatformEventsSnsTopicArnParameterName:       "arn:aws:sns:us-east-1:123456789012:worker-tasks",
+		EmailSubscriptionsTableNameParameterName:     "email-subscriptions",
+		ProcessedEventsTableNameParameterName:        "processed-events",
+		RelationalDatabaseDSNParameterName:           "user:password@tcp(localhost:3306)/score",
+		WebAppDomainNameParameterName:                "example.com",
+		MainTransactionalSendingAddressParameterName: "hello@example.com",
+		CognitoUserPoolIdParameterName:               "us-east-1_AbCdEf123",
+		CognitoAppClientIdParameterName:              "client-id",
+	}
+}
+
+func TestInitializeParametersValidation(t *testing.T) {
+	t.Run("valid parameters and defaults", func(t *testing.T) {
+		configService := New(AllParameterNames(), validParameters())
+		require.NoError(t, configService.InitializeParameters(context.Background()), "Valid parameters should be accepted")
+		require.Equal(t, 10, configService.WorkerPollConcurrency(), "Unset parameters should fall back to their default")
+		require.Equal(t, DeadLetterUnknownEvents, configService.WorkerUnknownEventPolicy(), "Unset parameters should fall back to their default")
+	})
+
+	t.Run("every problem is reported", func(t *testing.T) {
+		parameters := validParameters()
+		delete(parameters, WebAppDomainNameParameterName)
+		delete(parameters, CognitoAppClientIdParameterName)
+		parameters[PlatformEventsSnsTopicArnParameterName] = "worker-tasks"
+		parameters[MainTransactionalSendingAddressParameterName] = "not an address"
+		t.Setenv(string(WorkerPollConcurrencyParameterName), "0")
+
+		err := New(AllParameterNames(), parameters).InitializeParameters(context.Background())
+		require.Error(t, err, "Invalid configuration should be rejected")
+		require.True(t, errors.Is(err, ErrMissingParameter), "Missing parameters should be reported")
+		require.True(t, errors.Is(err, ErrInvalidParameter), "Invalid parameters should be reported")
+		for _, name := range []ConfigParameterName{
+			WebAppDomainNameParameterName,
+			CognitoAppClientIdParameterName,
+			PlatformEventsSnsTopicArnParameterName,
+			MainTransactionalSendingAddressParameterName,
+			WorkerPollConcurrencyParameterName,
+		} {
+			require.Contains(t, err.Error(), string(name), "Error should list every problem")
+		}
+	})
+}
+
+func TestRequiredUnlessSet(t *testing.T) {
+	parameters := validParameters()
+	delete(parameters, EmailSubscriptionsTableNameParameterName)
+	delete(parameters, ProcessedEventsTableNameParameterName)
+
+	err := New(AllParameterNames(), parameters).InitializeParameters(context.Background())
+	require.ErrorIs(t, err, ErrMissingParameter, "Table names should be required with DynamoDB")
+
+	t.Setenv(string(KeyValueStoreFileParameterName), "subscriptions.json")
+	err = New(AllParameterNames(), parameters).InitializeParameters(context.Background())
+	require.NoError(t, err, "Table names should not be required with a key value store file")
+}
+
+func TestLoadedParameters(t *testing.T) {
+	configService := New(
+		[]ConfigParameterName{WebAppDomainNameParameterName},
+		staticSource{WebAppDomainNameParameterName: "example.com"},
+	)
+	require.NoError(t, configService.InitializeParameters(context.Background()), "Parameters the mode doesn't load should not be required")
+	require.Equal(t, "example.com", configService.WebAppDomainName(), "Loaded parameters should be readable")
+	require.Panics(t, func() { configService.RelationalDatabaseConnectionString() }, "Reading a parameter that isn't loaded should panic")
+	require.Panics(t, func() { New([]ConfigParameterName{"undefined-parameter"}) }, "Loading an undefined parameter should panic")
+}
+
+func TestDescribe(t *testing.T) {
+	configService := New(
+		[]ConfigParameterName{RelationalDatabaseDSNParameterName, WebAppDomainNameParameterName, WorkerPollConcurrencyParameterName},
+		staticSource{RelationalDatabaseDSNParameterName: "user:password@tcp(localhost:3306)/score"},
+	)
+	require.Error(t, configService.InitializeParameters(context.Background()), "Missing parameters should fail initialization")
+
+	statuses := configService.Describe()
+	require.Equal(t, []ParameterStatus{
+		{Name: RelationalDatabaseDSNParameterName, Type: SecretParameter, Source: "static", Resolved: true, Value: redactedValue},
+		{Name: WebAppDomainNameParameterName, Type: StandardParameter, Problem: "missing required parameter: web-app-domain-name"},
+		{Name: WorkerPollConcurrencyParameterName, Type: EnvironmentParameter, Source: DefaultSourceName, Resolved: true, Value: "10"},
+	}, statuses, "Statuses should report sources, redact secrets and explain problems")
+}
+
+func TestValidators(t *testing.T) {
+	require.NoError(t, ValidateHostname("localhost:5173"), "Host names may carry a port")
+	require.Error(t, ValidateHostname("https://example.com"), "Host names may not carry a scheme")
+	require.NoError(t, ValidateUrl("http://localhost:9324"), "Local URLs should be accepted")
+	require.Error(t, ValidateUrl("localhost:9324"), "URLs must be absolute")
+	require.Error(t, ValidateDsn("not a dsn"), "Malformed DSNs should be rejected")
+	require.NoError(t, ValidateDsn("sqlite://:memory:"), "SQLite DSNs should be accepted")
+	require.Error(t, ValidateDsn("sqlite://"), "SQLite DSNs need a database path")
+	require.NoError(t, ValidateOneOf("a", "b")("b"), "Allowed values should be accepted")
+	require.Error(t, ValidateOneOf("a", "b")("c"), "Other values should be rejected")
+}
@@ -1,57 +1,258 @@
 package config
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
 // **********************************************************
 // This is where you add the parameters you want to retrieve
 
 const (
 	PlatformEventsSnsTopicArnParameterName       ConfigParameterName = "worker-tasks-topic-arn"
 	EmailSubscriptionsTableNameParameterName     ConfigParameterName = "email-subscriptions-table-name"
+	ProcessedEventsTableNameParameterName        ConfigParameterName = "processed-events-table-name"
 	RelationalDatabaseDSNParameterName           ConfigParameterName = "planetscale-core-dsn"
 	WebAppDomainNameParameterName                ConfigParameterName = "web-app-domain-name"
 	MainTransactionalSendingAddressParameterName ConfigParameterName = "main-transactional-sending-address"
+	CognitoUserPoolIdParameterName               ConfigParameterName = "cognito-user-pool-id"
+	CognitoAppClientIdParameterName              ConfigParameterName = "cognito-app-client-id"
+	WorkerTasksQueueUrlParameterName             ConfigParameterName = "worker-tasks-queue-url"
+	SqsEndpointUrlParameterName                  ConfigParameterName = "SCORE_SQS_ENDPOINT_URL"
+	WorkerPollConcurrencyParameterName           ConfigParameterName = "SCORE_WORKER_POLL_CONCURRENCY"
+	WorkerUnknownEventPolicyParameterName        ConfigParameterName = "SCORE_WORKER_UNKNOWN_EVENT_POLICY"
+	ConfigRefreshIntervalParameterName           ConfigParameterName = "SCORE_CONFIG_REFRESH_INTERVAL"
+	MySQLMaxOpenConnectionsParameterName         ConfigParameterName = "SCORE_MYSQL_MAX_OPEN_CONNECTIONS"
+	MySQLMaxIdleConnectionsParameterName         ConfigParameterName = "SCORE_MYSQL_MAX_IDLE_CONNECTIONS"
+	MySQLConnectionMaxLifetimeParameterName      ConfigParameterName = "SCORE_MYSQL_CONNECTION_MAX_LIFETIME"
+	MySQLConnectionMaxIdleTimeParameterName      ConfigParameterName = "SCORE_MYSQL_CONNECTION_MAX_IDLE_TIME"
+	KeyValueStoreFileParameterName               ConfigParameterName = "SCORE_KEY_VALUE_STORE_FILE"
+	AdminGroupParameterName                      ConfigParameterName = "SCORE_ADMIN_GROUP"
 )
 
 var paramDefinitions = []ConfigParameterDefinition{
 	{
 		ParameterName: PlatformEventsSnsTopicArnParameterName,
 		ParameterType: StandardParameter,
+		Required:      true,
+		Validator:     ValidateArn,
 	},
 	{
-		ParameterName: EmailSubscriptionsTableNameParameterName,
-		ParameterType: StandardParameter,
+		ParameterName:     EmailSubscriptionsTableNameParameterName,
+		ParameterType:     StandardParameter,
+		Required:          true,
+		RequiredUnlessSet: KeyValueStoreFileParameterName,
+	},
+	{
+		ParameterName:     ProcessedEventsTableNameParameterName,
+		ParameterType:     StandardParameter,
+		Required:          true,
+		RequiredUnlessSet: KeyValueStoreFileParameterName,
 	},
 	{
 		ParameterName: RelationalDatabaseDSNParameterName,
 		ParameterType: SecretParameter,
+		Required:      true,
+		Validator:     ValidateDsn,
 	},
 	{
 		ParameterName: WebAppDomainNameParameterName,
 		ParameterType: StandardParameter,
+		Required:      true,
+		Validator:     ValidateHostname,
 	},
 	{
 		ParameterName: MainTransactionalSendingAddressParameterName,
 		ParameterType: StandardParameter,
+		Required:      true,
+		Validator:     ValidateEmailAddress,
+	},
+	{
+		ParameterName: CognitoUserPoolIdParameterName,
+		ParameterType: StandardParameter,
+		Required:      true,
+	},
+	{
+		ParameterName: CognitoAppClientIdParameterName,
+		ParameterType: StandardParameter,
+		Required:      true,
+	},
+	{
+		ParameterName: WorkerTasksQueueUrlParameterName,
+		ParameterType: StandardParameter,
+		Validator:     ValidateUrl,
+	},
+	{
+		ParameterName: SqsEndpointUrlParameterName,
+		ParameterType: EnvironmentParameter,
+		Validator:     ValidateUrl,
+	},
+	{
+		ParameterName: WorkerPollConcurrencyParameterName,
+		ParameterType: EnvironmentParameter,
+		Default:       "10",
+		Validator:     ValidatePositiveInteger,
+	},
+	{
+		ParameterName: WorkerUnknownEventPolicyParameterName,
+		ParameterType: EnvironmentParameter,
+		Default:       DeadLetterUnknownEvents,
+		Validator:     ValidateOneOf(AcknowledgeUnknownEvents, DeadLetterUnknownEvents),
+	},
+	{
+		ParameterName: ConfigRefreshIntervalParameterName,
+		ParameterType: EnvironmentParameter,
+		Validator:     ValidateDuration,
+	},
+	{
+		ParameterName: MySQLMaxOpenConnectionsParameterName,
+		ParameterType: EnvironmentParameter,
+		Default:       "10",
+		Validator:     ValidatePositiveInteger,
+	},
+	{
+		ParameterName: MySQLMaxIdleConnectionsParameterName,
+		ParameterType: EnvironmentParameter,
+		Default:       "5",
+		Validator:     ValidatePositiveInteger,
+	},
+	{
+		ParameterName: MySQLConnectionMaxLifetimeParameterName,
+		ParameterType: EnvironmentParameter,
+		Default:       "5m",
+		Validator:     ValidateDuration,
+	},
+	{
+		ParameterName: MySQLConnectionMaxIdleTimeParameterName,
+		ParameterType: EnvironmentParameter,
+		Default:       "1m",
+		Validator:     ValidateDuration,
+	},
+	{
+		ParameterName: KeyValueStoreFileParameterName,
+		ParameterType: EnvironmentParameter,
+	},
+	{
+		ParameterName: AdminGroupParameterName,
+		ParameterType: EnvironmentParameter,
+		Default:       "admin",
 	},
 }
 
+// MySQLParameters are the parameters any mode using the relational database must load
+var MySQLParameters = []ConfigParameterName{
+	RelationalDatabaseDSNParameterName,
+	MySQLMaxOpenConnectionsParameterName,
+	MySQLMaxIdleConnectionsParameterName,
+	MySQLConnectionMaxLifetimeParameterName,
+	MySQLConnectionMaxIdleTimeParameterName,
+}
+
+// Values of the worker unknown event policy parameter
+const (
+	AcknowledgeUnknownEvents = "acknowledge"
+	DeadLetterUnknownEvents  = "dead-letter"
+)
+
 func (s *Config) PlatformEventsTopicArn() string {
-	return s.parameters[PlatformEventsSnsTopicArnParameterName]
+	return s.get(PlatformEventsSnsTopicArnParameterName)
 }
 
 func (s *Config) EmailSubscriptionsTableName() string {
-	return s.parameters[EmailSubscriptionsTableNameParameterName]
+	return s.get(EmailSubscriptionsTableNameParameterName)
+}
+
+func (s *Config) ProcessedEventsTableName() string {
+	return s.get(ProcessedEventsTableNameParameterName)
 }
 
 func (s *Config) RelationalDatabaseConnectionString() string {
-	return s.parameters[RelationalDatabaseDSNParameterName]
+	return s.get(RelationalDatabaseDSNParameterName)
 }
 
 func (s *Config) WebAppDomainName() string {
-	return s.parameters[WebAppDomainNameParameterName]
+	return s.get(WebAppDomainNameParameterName)
 }
 
 func (s *Config) MainTransactionalSendingAddress() string {
-	return s.parameters[MainTransactionalSendingAddressParameterName]
+	return s.get(MainTransactionalSendingAddressParameterName)
+}
+
+// CognitoIssuer returns the issuer URL of tokens signed by the Cognito user pool. User pool IDs are
+// prefixed with the region they live in (e.g. us-east-1_AbCdEf123).
+func (s *Config) CognitoIssuer() string {
+	userPoolId := s.get(CognitoUserPoolIdParameterName)
+	region := strings.Split(userPoolId, "_")[0]
+	return fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s", region, userPoolId)
+}
+
+// CognitoJwksUrl returns the JSON Web Key Set URL of the Cognito user pool
+func (s *Config) CognitoJwksUrl() string {
+	return s.CognitoIssuer() + "/.well-known/jwks.json"
+}
+
+func (s *Config) CognitoAppClientId() string {
+	return s.get(CognitoAppClientIdParameterName)
+}
+
+func (s *Config) WorkerTasksQueueUrl() string {
+	return s.get(WorkerTasksQueueUrlParameterName)
+}
+
+// SqsEndpointUrl overrides the SQS endpoint, e.g. to poll a local SQS stand-in. Empty means the AWS default.
+func (s *Config) SqsEndpointUrl() string {
+	return s.get(SqsEndpointUrlParameterName)
+}
+
+// WorkerPollConcurrency is the maximum number of messages the polling worker processes at once
+func (s *Config) WorkerPollConcurrency() int {
+	concurrency, _ := strconv.Atoi(s.get(WorkerPollConcurrencyParameterName))
+	return concurrency
+}
+
+// WorkerUnknownEventPolicy is either AcknowledgeUnknownEvents or DeadLetterUnknownEvents (the default)
+func (s *Config) WorkerUnknownEventPolicy() string {
+	return s.get(WorkerUnknownEventPolicyParameterName)
+}
+
+// ConfigRefreshInterval is how often long running modes re-read their config, e.g. "5m". Zero disables refreshing.
+func (s *Config) ConfigRefreshInterval() time.Duration {
+	interval, _ := time.ParseDuration(s.get(ConfigRefreshIntervalParameterName))
+	return interval
+}
+
+// MySQLMaxOpenConnections caps the connections the pool opens, which PlanetScale limits per database
+func (s *Config) MySQLMaxOpenConnections() int {
+	connections, _ := strconv.Atoi(s.get(MySQLMaxOpenConnectionsParameterName))
+	return connections
+}
+
+func (s *Config) MySQLMaxIdleConnections() int {
+	connections, _ := strconv.Atoi(s.get(MySQLMaxIdleConnectionsParameterName))
+	return connections
+}
+
+func (s *Config) MySQLConnectionMaxLifetime() time.Duration {
+	lifetime, _ := time.ParseDuration(s.get(MySQLConnectionMaxLifetimeParameterName))
+	return lifetime
+}
+
+func (s *Config) MySQLConnectionMaxIdleTime() time.Duration {
+	idleTime, _ := time.ParseDuration(s.get(MySQLConnectionMaxIdleTimeParameterName))
+	return idleTime
+}
+
+// KeyValueStoreFile is a JSON file that replaces DynamoDB as the key value store for local runs.
+// Empty means DynamoDB is used.
+func (s *Config) KeyValueStoreFile() string {
+	return s.get(KeyValueStoreFileParameterName)
+}
+
+// AdminGroup is the Cognito group whose members may use the admin endpoints
+func (s *Config) AdminGroup() string {
+	return s.get(AdminGroupParameterName)
 }
 
 // **********************************************************
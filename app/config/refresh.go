@@ -0,0 +1,69 @@
+package config
+
+import (
+	"context"
+	"time"
+)
+
+// Subscriber is called with the new value of a parameter after a refresh changed it
+type Subscriber func(value string)
+
+// Logger is used by the refresher to report refreshes and failures
+type Logger interface {
+	InfoWithContext(message string, keysAndValues ...interface{})
+	ErrorWithContext(message string, keysAndValues ...interface{})
+}
+
+// Subscribe registers a function to call whenever a refresh changes the parameter. Subscribers are
+// called synchronously by the refresher, after the new values are visible to readers.
+func (s *Config) Subscribe(name ConfigParameterName, subscriber Subscriber) {
+	s.subscribersMutex.Lock()
+	defer s.subscribersMutex.Unlock()
+	s.subscribers[name] = append(s.subscribers[name], subscriber)
+}
+
+// Refresh re-reads every loaded parameter and swaps in the new values at once, then notifies the
+// subscribers of the parameters that changed. If the sources fail or the new values are invalid,
+// the current values are kept and the error is returned.
+func (s *Config) Refresh(ctx context.Context) ([]ConfigParameterName, error) {
+	snapshot, err := s.resolveParameters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	previous := s.parameters.Swap(snapshot)
+
+	changed := []ConfigParameterName{}
+	for _, definition := range s.definitions {
+		if previous.values[definition.ParameterName] != snapshot.values[definition.ParameterName] {
+			changed = append(changed, definition.ParameterName)
+		}
+	}
+	for _, name := range changed {
+		s.subscribersMutex.Lock()
+		subscribers := append([]Subscriber{}, s.subscribers[name]...)
+		s.subscribersMutex.Unlock()
+		for _, subscriber := range subscribers {
+			subscriber(snapshot.values[name])
+		}
+	}
+	return changed, nil
+}
+
+// RunRefresher refreshes the config on every interval until the context is cancelled
+func (s *Config) RunRefresher(ctx context.Context, interval time.Duration, logger Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := s.Refresh(ctx)
+			if err != nil {
+				logger.ErrorWithContext("error refreshing config, keeping current values", "error", err.Error())
+			} else if len(changed) > 0 {
+				logger.InfoWithContext("config refreshed", "changedParameters", changed)
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefresh(t *testing.T) {
+	source := validParameters()
+	configService := New(AllParameterNames(), source)
+	require.NoError(t, configService.InitializeParameters(context.Background()), "Initializing parameters should succeed")
+	notifiedValues := []string{}
+	configService.Subscribe(RelationalDatabaseDSNParameterName, func(value string) {
+		notifiedValues = append(notifiedValues, value)
+	})
+
+	t.Run("unchanged values", func(t *testing.T) {
+		changed, err := configService.Refresh(context.Background())
+		require.NoError(t, err, "Refreshing should succeed")
+		require.Empty(t, changed, "No parameters should have changed")
+		require.Empty(t, notifiedValues, "Subscribers should not be notified")
+	})
+
+	t.Run("changed values", func(t *testing.T) {
+		source[RelationalDatabaseDSNParameterName] = "user:rotated@tcp(localhost:3306)/score"
+		changed, err := configService.Refresh(context.Background())
+		require.NoError(t, err, "Refreshing should succeed")
+		require.Equal(t, []ConfigParameterName{RelationalDatabaseDSNParameterName}, changed, "The changed parameter should be reported")
+		require.Equal(t, "user:rotated@tcp(localhost:3306)/score", configService.RelationalDatabaseConnectionString(), "The new value should be visible")
+		require.Equal(t, []string{"user:rotated@tcp(localhost:3306)/score"}, notifiedValues, "Subscribers should get the new value")
+	})
+
+	t.Run("invalid values are not applied", func(t *testing.T) {
+		source[WebAppDomainNameParameterName] = "rotated.example.com"
+		delete(source, MainTransactionalSendingAddressParameterName)
+		_, err := configService.Refresh(context.Background())
+		require.Error(t, err, "Refreshing to an invalid config should fail")
+		require.Equal(t, "example.com", configService.WebAppDomainName(), "The current values should be kept")
+	})
+}
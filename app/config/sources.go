@@ -0,0 +1,208 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables selecting where parameters are read from
+const (
+	// ConfigSourcesEnvVar is a comma separated list of sources in precedence order, e.g. "env,file,ssm"
+	ConfigSourcesEnvVar = "SCORE_CONFIG_SOURCES"
+	// ConfigFileEnvVar is the path of the YAML, JSON or dotenv file read by the file source
+	ConfigFileEnvVar = "SCORE_CONFIG_FILE"
+	// SsmPrefixEnvVar is the SSM path holding the parameters of an environment, e.g. "/score/prod/"
+	SsmPrefixEnvVar = "SCORE_SSM_PREFIX"
+)
+
+const (
+	SsmSourceName  = "ssm"
+	EnvSourceName  = "env"
+	FileSourceName = "file"
+	// DefaultSourceName marks parameters that fell back to their default value
+	DefaultSourceName = "default"
+)
+
+// defaultConfigSources keeps parameters in SSM unless told otherwise
+const defaultConfigSources = SsmSourceName
+
+// ParameterSource resolves standard and secret parameters. Sources only return the parameters they
+// have a value for, so that the next source in precedence order can provide the others.
+type ParameterSource interface {
+	Name() string
+	GetParameters(ctx context.Context, definitions []ConfigParameterDefinition) (map[ConfigParameterName]string, error)
+}
+
+// SourcesFromEnvironment builds the parameter sources listed in SCORE_CONFIG_SOURCES
+func SourcesFromEnvironment(awsSession *session.Session) ([]ParameterSource, error) {
+	sourceNames := os.Getenv(ConfigSourcesEnvVar)
+	if sourceNames == "" {
+		sourceNames = defaultConfigSources
+	}
+	sources := []ParameterSource{}
+	for _, sourceName := range strings.Split(sourceNames, ",") {
+		switch strings.TrimSpace(sourceName) {
+		case SsmSourceName:
+			sources = append(sources, NewSsmSource(ssm.New(awsSession), os.Getenv(SsmPrefixEnvVar)))
+		case EnvSourceName:
+			sources = append(sources, NewEnvSource())
+		case FileSourceName:
+			fileSource, err := NewFileSource(os.Getenv(ConfigFileEnvVar))
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, fileSource)
+		default:
+			return nil, fmt.Errorf("unknown config source %q in %s", sourceName, ConfigSourcesEnvVar)
+		}
+	}
+	return sources, nil
+}
+
+// EnvVarName returns the environment variable holding a parameter, e.g. SCORE_WEB_APP_DOMAIN_NAME for web-app-domain-name
+func EnvVarName(name ConfigParameterName) string {
+	return "SCORE_" + strings.ToUpper(strings.NewReplacer("-", "_", "/", "_", ".", "_").Replace(string(name)))
+}
+
+// EnvSource reads each parameter from its SCORE_ prefixed environment variable (see EnvVarName)
+type EnvSource struct{}
+
+func NewEnvSource() *EnvSource {
+	return &EnvSource{}
+}
+
+func (s *EnvSource) Name() string {
+	return EnvSourceName
+}
+
+func (s *EnvSource) GetParameters(ctx context.Context, definitions []ConfigParameterDefinition) (map[ConfigParameterName]string, error) {
+	parameters := map[ConfigParameterName]string{}
+	for _, definition := range definitions {
+		if value, ok := os.LookupEnv(EnvVarName(definition.ParameterName)); ok {
+			parameters[definition.ParameterName] = value
+		}
+	}
+	return parameters, nil
+}
+
+// FileSource reads parameters from a local YAML, JSON or dotenv file, picked by the file extension.
+// Values are keyed by parameter name (web-app-domain-name) or environment variable name (SCORE_WEB_APP_DOMAIN_NAME).
+// The file is read again on every retrieval so that config refreshes pick up edits.
+type FileSource struct {
+	path string
+}
+
+// NewFileSource reads the file once so that a missing or malformed file is reported at startup
+func NewFileSource(path string) (*FileSource, error) {
+	if path == "" {
+		return nil, fmt.Errorf("the file config source requires %s to be set", ConfigFileEnvVar)
+	}
+	source := &FileSource{
+		path: path,
+	}
+	if _, err := source.readValues(); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+func (s *FileSource) Name() string {
+	return FileSourceName
+}
+
+func (s *FileSource) GetParameters(ctx context.Context, definitions []ConfigParameterDefinition) (map[ConfigParameterName]string, error) {
+	values, err := s.readValues()
+	if err != nil {
+		return nil, err
+	}
+	parameters := map[ConfigParameterName]string{}
+	for _, definition := range definitions {
+		if value, ok := values[string(definition.ParameterName)]; ok {
+			parameters[definition.ParameterName] = value
+		} else if value, ok := values[EnvVarName(definition.ParameterName)]; ok {
+			parameters[definition.ParameterName] = value
+		}
+	}
+	return parameters, nil
+}
+
+func (s *FileSource) readValues() (map[string]string, error) {
+	contents, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file => %v", err.Error())
+	}
+	values, err := parseConfigFile(filepath.Ext(s.path), contents)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config file %s => %v", s.path, err.Error())
+	}
+	return values, nil
+}
+
+func parseConfigFile(extension string, contents []byte) (map[string]string, error) {
+	switch strings.ToLower(extension) {
+	case ".yaml", ".yml":
+		rawValues := map[string]interface{}{}
+		if err := yaml.Unmarshal(contents, &rawValues); err != nil {
+			return nil, err
+		}
+		return stringifyValues(rawValues), nil
+	case ".json":
+		rawValues := map[string]interface{}{}
+		if err := json.Unmarshal(contents, &rawValues); err != nil {
+			return nil, err
+		}
+		return stringifyValues(rawValues), nil
+	case ".env":
+		return parseDotenv(contents)
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q", extension)
+	}
+}
+
+// stringifyValues converts scalar values such as numbers and booleans to their string form
+func stringifyValues(rawValues map[string]interface{}) map[string]string {
+	values := map[string]string{}
+	for key, value := range rawValues {
+		if value == nil {
+			continue
+		}
+		values[key] = fmt.Sprint(value)
+	}
+	return values
+}
+
+// parseDotenv parses KEY=VALUE lines, ignoring blank lines and # comments. Values may be quoted
+// and lines may be prefixed with "export".
+func parseDotenv(contents []byte) (map[string]string, error) {
+	values := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d is not a KEY=VALUE pair", lineNumber)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	return values, scanner.Err()
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type staticSource map[ConfigParameterName]string
+
+func (s staticSource) Name() string {
+	return "static"
+}
+
+func (s staticSource) GetParameters(ctx context.Context, definitions []ConfigParameterDefinition) (map[ConfigParameterName]string, error) {
+	parameters := map[ConfigParameterName]string{}
+	for _, definition := range definitions {
+		if value, ok := s[definition.ParameterName]; ok {
+			parameters[definition.ParameterName] = value
+		}
+	}
+	return parameters, nil
+}
+
+func TestSourcePrecedence(t *testing.T) {
+	configService := New(
+		AllParameterNames(),
+		staticSource{WebAppDomainNameParameterName: "first.example.com"},
+		staticSource{
+			WebAppDomainNameParameterName:                "second.example.com",
+			MainTransactionalSendingAddressParameterName: "hello@example.com",
+		},
+		validParameters(),
+	)
+	require.NoError(t, configService.InitializeParameters(context.Background()), "Initializing parameters should succeed")
+	require.Equal(t, "first.example.com", configService.WebAppDomainName(), "The first source with a value should win")
+	require.Equal(t, "hello@example.com", configService.MainTransactionalSendingAddress(), "Later sources should fill in missing values")
+}
+
+func TestEnvSource(t *testing.T) {
+	t.Setenv("SCORE_WEB_APP_DOMAIN_NAME", "env.example.com")
+	parameters, err := NewEnvSource().GetParameters(context.Background(), paramDefinitions)
+	require.NoError(t, err, "Reading the environment should succeed")
+	require.Equal(t, "env.example.com", parameters[WebAppDomainNameParameterName], "Parameter should be read from its SCORE_ variable")
+	_, found := parameters[MainTransactionalSendingAddressParameterName]
+	require.False(t, found, "Unset variables should be left to the next source")
+}
+
+func TestFileSource(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": "web-app-domain-name: file.example.com\n",
+		"config.json": `{"web-app-domain-name": "file.example.com"}`,
+		"config.env":  "# local overrides\nexport SCORE_WEB_APP_DOMAIN_NAME=\"file.example.com\"\n",
+	}
+	for fileName, contents := range files {
+		t.Run(fileName, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), fileName)
+			require.NoError(t, os.WriteFile(path, []byte(contents), 0o600), "Writing the config file should succeed")
+			source, err := NewFileSource(path)
+			require.NoError(t, err, "Loading the config file should succeed")
+			parameters, err := source.GetParameters(context.Background(), paramDefinitions)
+			require.NoError(t, err, "Reading parameters should succeed")
+			require.Equal(t, "file.example.com", parameters[WebAppDomainNameParameterName], "Parameter should be read from the file")
+		})
+	}
+
+	t.Run("unsupported extension", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.toml")
+		require.NoError(t, os.WriteFile(path, []byte(""), 0o600), "Writing the config file should succeed")
+		_, err := NewFileSource(path)
+		require.Error(t, err, "Unsupported file formats should be rejected")
+	})
+}
+
+func TestSourcesFromEnvironment(t *testing.T) {
+	t.Setenv(ConfigSourcesEnvVar, "env,unknown")
+	_, err := SourcesFromEnvironment(nil)
+	require.Error(t, err, "Unknown sources should be rejected")
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// ssmGetParametersMaxNames is the maximum number of names SSM accepts in a single GetParameters call
+const ssmGetParametersMaxNames = 10
+
+type ssmClient interface {
+	GetParametersWithContext(ctx aws.Context, input *ssm.GetParametersInput, opts ...request.Option) (*ssm.GetParametersOutput, error)
+	GetParametersByPathPagesWithContext(ctx aws.Context, input *ssm.GetParametersByPathInput, fn func(*ssm.GetParametersByPathOutput, bool) bool, opts ...request.Option) error
+}
+
+// SsmSource reads parameters from AWS Systems Manager Parameter Store, decrypting secret parameters.
+// Parameters that don't exist in SSM are left to the next source, or reported as missing by the config.
+//
+// Without a prefix, parameters are read by name. With a prefix such as "/score/prod/", every parameter
+// under that path is read and "/score/prod/web-app-domain-name" resolves web-app-domain-name.
+type SsmSource struct {
+	systemManager ssmClient
+	prefix        string
+}
+
+func NewSsmSource(systemManager ssmClient, prefix string) *SsmSource {
+	if prefix != "" {
+		prefix = "/" + strings.Trim(prefix, "/") + "/"
+	}
+	return &SsmSource{
+		systemManager: systemManager,
+		prefix:        prefix,
+	}
+}
+
+func (s *SsmSource) Name() string {
+	return SsmSourceName
+}
+
+func (s *SsmSource) GetParameters(ctx context.Context, definitions []ConfigParameterDefinition) (map[ConfigParameterName]string, error) {
+	parameters := map[ConfigParameterName]string{}
+	if s.prefix != "" {
+		if err := s.retrieveParametersByPath(ctx, definitions, parameters); err != nil {
+			return nil, fmt.Errorf("error while retrieving SSM parameters under %s => %v", s.prefix, err.Error())
+		}
+		return parameters, nil
+	}
+	if err := s.retrieveParameters(ctx, definitions, StandardParameter, parameters); err != nil {
+		return nil, fmt.Errorf("error while retrieving standard SSM parameters => %v", err.Error())
+	}
+	if err := s.retrieveParameters(ctx, definitions, SecretParameter, parameters); err != nil {
+		return nil, fmt.Errorf("error while retrieving secret SSM parameters => %v", err.Error())
+	}
+	return parameters, nil
+}
+
+// retrieveParameters reads the parameters of a type by name, in batches of the most names SSM accepts
+func (s *SsmSource) retrieveParameters(
+	ctx context.Context,
+	definitions []ConfigParameterDefinition,
+	parameterType ConfigParameterType,
+	parameters map[ConfigParameterName]string,
+) error {
+	paramNames := []*string{}
+	for _, definition := range definitions {
+		if definition.ParameterType == parameterType {
+			paramNames = append(paramNames, aws.String(string(definition.ParameterName)))
+		}
+	}
+	for start := 0; start < len(paramNames); start += ssmGetParametersMaxNames {
+		end := start + ssmGetParametersMaxNames
+		if end > len(paramNames) {
+			end = len(paramNames)
+		}
+		paramOutput, err := s.systemManager.GetParametersWithContext(ctx, &ssm.GetParametersInput{
+			Names:          paramNames[start:end],
+			WithDecryption: aws.Bool(parameterType == SecretParameter),
+		})
+		if err != nil {
+			return err
+		}
+		for _, param := range paramOutput.Parameters {
+			if _, exists := parameters[ConfigParameterName(*param.Name)]; exists {
+				return fmt.Errorf("duplicate parameter name: %s", string(*param.Name))
+			}
+			parameters[ConfigParameterName(*param.Name)] = *param.Value
+		}
+	}
+	return nil
+}
+
+// retrieveParametersByPath reads every parameter under the prefix and keeps the defined ones,
+// named without the prefix
+func (s *SsmSource) retrieveParametersByPath(
+	ctx context.Context,
+	definitions []ConfigParameterDefinition,
+	parameters map[ConfigParameterName]string,
+) error {
+	definedNames := map[ConfigParameterName]bool{}
+	for _, definition := range definitions {
+		definedNames[definition.ParameterName] = true
+	}
+	return s.systemManager.GetParametersByPathPagesWithContext(ctx, &ssm.GetParametersByPathInput{
+		Path:           aws.String(s.prefix),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	}, func(page *ssm.GetParametersByPathOutput, lastPage bool) bool {
+		for _, param := range page.Parameters {
+			name := ConfigParameterName(strings.TrimPrefix(aws.StringValue(param.Name), s.prefix))
+			if definedNames[name] {
+				parameters[name] = aws.StringValue(param.Value)
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSsmClient struct {
+	values          map[string]string
+	getParamsCalls  [][]string
+	requestedPrefix string
+}
+
+func (s *fakeSsmClient) GetParametersWithContext(ctx aws.Context, input *ssm.GetParametersInput, opts ...request.Option) (*ssm.GetParametersOutput, error) {
+	names := aws.StringValueSlice(input.Names)
+	if len(names) > ssmGetParametersMaxNames {
+		return nil, fmt.Errorf("too many names: %d", len(names))
+	}
+	s.getParamsCalls = append(s.getParamsCalls, names)
+	output := &ssm.GetParametersOutput{}
+	for _, name := range names {
+		if value, ok := s.values[name]; ok {
+			output.Parameters = append(output.Parameters, &ssm.Parameter{Name: aws.String(name), Value: aws.String(value)})
+		} else {
+			output.InvalidParameters = append(output.InvalidParameters, aws.String(name))
+		}
+	}
+	return output, nil
+}
+
+func (s *fakeSsmClient) GetParametersByPathPagesWithContext(ctx aws.Context, input *ssm.GetParametersByPathInput, fn func(*ssm.GetParametersByPathOutput, bool) bool, opts ...request.Option) error {
+	s.requestedPrefix = aws.StringValue(input.Path)
+	// Return one parameter per page to exercise pagination
+	for name, value := range s.values {
+		page := &ssm.GetParametersByPathOutput{
+			Parameters: []*ssm.Parameter{{Name: aws.String(name), Value: aws.String(value)}},
+		}
+		if !fn(page, false) {
+			break
+		}
+	}
+	return nil
+}
+
+func TestSsmSourceChunksNames(t *testing.T) {
+	definitions := []ConfigParameterDefinition{}
+	values := map[string]string{}
+	for i := 0; i < 23; i++ {
+		name := fmt.Sprintf("parameter-%d", i)
+		definitions = append(definitions, ConfigParameterDefinition{ParameterName: ConfigParameterName(name), ParameterType: StandardParameter})
+		values[name] = fmt.Sprintf("value-%d", i)
+	}
+	client := &fakeSsmClient{values: values}
+
+	parameters, err := NewSsmSource(client, "").GetParameters(context.Background(), definitions)
+	require.NoError(t, err, "Retrieving parameters should succeed")
+	require.Len(t, parameters, 23, "Every parameter should be retrieved")
+	require.Len(t, client.getParamsCalls, 3, "Names should be requested in batches of 10")
+}
+
+func TestSsmSourceByPath(t *testing.T) {
+	client := &fakeSsmClient{values: map[string]string{
+		"/score/prod/web-app-domain-name":  "example.com",
+		"/score/prod/planetscale-core-dsn": "user:password@tcp(localhost:3306)/score",
+		"/score/prod/unrelated-parameter":  "ignored",
+	}}
+
+	parameters, err := NewSsmSource(client, "score/prod").GetParameters(context.Background(), paramDefinitions)
+	require.NoError(t, err, "Retrieving parameters should succeed")
+	require.Equal(t, "/score/prod/", client.requestedPrefix, "Prefix should be normalized to an SSM path")
+	require.Equal(t, map[ConfigParameterName]string{
+		WebAppDomainNameParameterName:      "example.com",
+		RelationalDatabaseDSNParameterName: "user:password@tcp(localhost:3306)/score",
+	}, parameters, "Prefixed names should map back onto the defined parameters")
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	ErrMissingParameter = errors.New("missing required parameter")
+	ErrInvalidParameter = errors.New("invalid parameter")
+)
+
+// Validator checks the value of a parameter that has been resolved
+type Validator func(value string) error
+
+var (
+	arnPattern      = regexp.MustCompile(`^arn:aws[a-zA-Z-]*:[a-z0-9-]+:[a-z0-9-]*:[0-9]{0,12}:.+$`)
+	hostnamePattern = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(:[0-9]{1,5})?$`)
+)
+
+// ValidateArn accepts Amazon Resource Names such as arn:aws:sns:us-east-1:123456789012:topic
+func ValidateArn(value string) error {
+	if !arnPattern.MatchString(value) {
+		return errors.New("not an ARN")
+	}
+	return nil
+}
+
+// ValidateUrl accepts absolute http and https URLs
+func ValidateUrl(value string) error {
+	parsedUrl, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") || parsedUrl.Host == "" {
+		return errors.New("not an absolute http(s) URL")
+	}
+	return nil
+}
+
+// ValidateHostname accepts a host name with an optional port, without a scheme or path
+func ValidateHostname(value string) error {
+	if len(value) > 253 || !hostnamePattern.MatchString(value) {
+		return errors.New("not a host name")
+	}
+	return nil
+}
+
+// SQLiteDsnScheme prefixes data source names that select the SQLite backend instead of MySQL,
+// e.g. sqlite://score.db or sqlite://:memory:
+const SQLiteDsnScheme = "sqlite://"
+
+// ValidateDsn accepts MySQL data source names and SQLite ones prefixed with SQLiteDsnScheme
+func ValidateDsn(value string) error {
+	if strings.HasPrefix(value, SQLiteDsnScheme) {
+		if strings.TrimPrefix(value, SQLiteDsnScheme) == "" {
+			return fmt.Errorf("SQLite DSN has no database path")
+		}
+		return nil
+	}
+	if _, err := mysql.ParseDSN(value); err != nil {
+		return fmt.Errorf("not a MySQL DSN: %v", err)
+	}
+	return nil
+}
+
+// ValidateEmailAddress accepts a bare email address or one with a display name
+func ValidateEmailAddress(value string) error {
+	if _, err := mail.ParseAddress(value); err != nil {
+		return fmt.Errorf("not an email address: %v", err)
+	}
+	return nil
+}
+
+// ValidatePositiveInteger accepts integers greater than zero
+func ValidatePositiveInteger(value string) error {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 {
+		return errors.New("not a positive integer")
+	}
+	return nil
+}
+
+// ValidateOneOf accepts one of the allowed values
+func ValidateOneOf(allowedValues ...string) Validator {
+	return func(value string) error {
+		for _, allowedValue := range allowedValues {
+			if value == allowedValue {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(allowedValues, ", "))
+	}
+}
+
+// ValidateDuration accepts non-negative Go durations such as "30s" or "5m"
+func ValidateDuration(value string) error {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return errors.New("not a duration")
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package correlation
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// RequestIdHeader is the HTTP header carrying the correlation ID of a request
+const RequestIdHeader = "X-Request-ID"
+
+// maxIdLength bounds the length of correlation IDs accepted from clients
+const maxIdLength = 128
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the correlation ID
+func NewContext(ctx context.Context, correlationId string) context.Context {
+	return context.WithValue(ctx, contextKey{}, correlationId)
+}
+
+// FromContext returns the correlation ID carried by the context, or an empty string if there is none
+func FromContext(ctx context.Context) string {
+	correlationId, _ := ctx.Value(contextKey{}).(string)
+	return correlationId
+}
+
+// NewId generates a new correlation ID
+func NewId() string {
+	return uuid.New().String()
+}
+
+// IsValidId reports whether a correlation ID received from a client is safe to propagate and log
+func IsValidId(correlationId string) bool {
+	if correlationId == "" || len(correlationId) > maxIdLength {
+		return false
+	}
+	for _, char := range correlationId {
+		if char < '!' || char > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -1,11 +1,20 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 
 	"go.uber.org/zap"
 )
 
+// Interface is the logging interface of Logger. Consumers declare their own logger interfaces, which
+// loggers of this type satisfy.
+type Interface interface {
+	InfoWithContext(message string, keysAndValues ...interface{})
+	ErrorWithContext(message string, keysAndValues ...interface{})
+	DebugWithContext(message string, keysAndValues ...interface{})
+}
+
 type Logger struct {
 	logger        *zap.Logger
 	sugaredLogger *zap.SugaredLogger
@@ -39,3 +48,36 @@ func (s *Logger) ErrorWithContext(message string, keysAndValues ...interface{})
 func (s *Logger) DebugWithContext(message string, keysAndValues ...interface{}) {
 	s.sugaredLogger.Debugw(message, keysAndValues...)
 }
+
+// With returns a logger that adds the given key value pairs to every message
+func (s *Logger) With(keysAndValues ...interface{}) Interface {
+	sugaredLogger := s.sugaredLogger.With(keysAndValues...)
+	return &Logger{
+		logger:        sugaredLogger.Desugar(),
+		sugaredLogger: sugaredLogger,
+	}
+}
+
+// NewNop returns a logger that discards every message
+func NewNop() *Logger {
+	logger := zap.NewNop()
+	return &Logger{
+		logger:        logger,
+		sugaredLogger: logger.Sugar(),
+	}
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying a request scoped logger
+func NewContext(ctx context.Context, logger Interface) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the request scoped logger carried by the context, or fallback if there is none
+func FromContext(ctx context.Context, fallback Interface) Interface {
+	if logger, ok := ctx.Value(contextKey{}).(Interface); ok {
+		return logger
+	}
+	return fallback
+}
@@ -1,15 +1,114 @@
 package models
 
+import (
+	"fmt"
+	"strconv"
+)
+
 type EmailSubscription struct {
-	Email             string `json:"email"`
-	Verified          bool   `json:"email_verified"`
-	HasComplaint      bool   `json:"has_complaint"`
-	HasBounce         bool   `json:"has_bounce"`
-	ComplaintDetails  string `json:"complaint_details"`
-	ComplaintDateUnix int64  `json:"complaint_date"`
-	BounceType        string `json:"bounce_type"`
-	BounceDetails     string `json:"bounce_details"`
-	BounceDateUnix    int64  `json:"bounce_date"`
-	CreationDate      int64  `json:"creation_date"`
-	SubscriptionToken string `json:"subscription_token"`
+	Email                       string `json:"email"`
+	Verified                    bool   `json:"email_verified"`
+	HasComplaint                bool   `json:"has_complaint"`
+	HasBounce                   bool   `json:"has_bounce"`
+	ComplaintDetails            string `json:"complaint_details"`
+	ComplaintDateUnix           int64  `json:"complaint_date"`
+	BounceType                  string `json:"bounce_type"`
+	BounceDetails               string `json:"bounce_details"`
+	BounceDateUnix              int64  `json:"bounce_date"`
+	CreationDate                int64  `json:"creation_date"`
+	SubscriptionToken           string `json:"subscription_token"`
+	SubscriptionTokenIssuedUnix int64  `json:"subscription_token_issued"`
+	SubscriptionTokenExpiryUnix int64  `json:"subscription_token_expiry"`
+	UnsubscribeToken            string `json:"unsubscribe_token"`
+	Unsubscribed                bool   `json:"unsubscribed"`
+	UnsubscribeDateUnix         int64  `json:"unsubscribe_date"`
+}
+
+// EmailSubscriptionSummary is the listing view of a subscription, which leaves out its tokens
+type EmailSubscriptionSummary struct {
+	Email               string `json:"email"`
+	Verified            bool   `json:"email_verified"`
+	HasComplaint        bool   `json:"has_complaint"`
+	ComplaintDetails    string `json:"complaint_details"`
+	ComplaintDateUnix   int64  `json:"complaint_date"`
+	HasBounce           bool   `json:"has_bounce"`
+	BounceType          string `json:"bounce_type"`
+	BounceDetails       string `json:"bounce_details"`
+	BounceDateUnix      int64  `json:"bounce_date"`
+	CreationDate        int64  `json:"creation_date"`
+	Unsubscribed        bool   `json:"unsubscribed"`
+	UnsubscribeDateUnix int64  `json:"unsubscribe_date"`
+}
+
+func (s *EmailSubscription) Summary() EmailSubscriptionSummary {
+	return EmailSubscriptionSummary{
+		Email:               s.Email,
+		Verified:            s.Verified,
+		HasComplaint:        s.HasComplaint,
+		ComplaintDetails:    s.ComplaintDetails,
+		ComplaintDateUnix:   s.ComplaintDateUnix,
+		HasBounce:           s.HasBounce,
+		BounceType:          s.BounceType,
+		BounceDetails:       s.BounceDetails,
+		BounceDateUnix:      s.BounceDateUnix,
+		CreationDate:        s.CreationDate,
+		Unsubscribed:        s.Unsubscribed,
+		UnsubscribeDateUnix: s.UnsubscribeDateUnix,
+	}
+}
+
+// Names of the email subscription filters, as used in query strings and on the command line
+const (
+	VerifiedFilter     = "verified"
+	BouncedFilter      = "bounced"
+	ComplainedFilter   = "complained"
+	UnsubscribedFilter = "unsubscribed"
+)
+
+// EmailSubscriptionFilter narrows a subscription listing. Nil fields match any value.
+type EmailSubscriptionFilter struct {
+	Verified     *bool
+	Bounced      *bool
+	Complained   *bool
+	Unsubscribed *bool
+}
+
+// Set sets the filter with the name to a boolean value such as "true" or "false"
+func (s *EmailSubscriptionFilter) Set(name, value string) error {
+	parsedValue, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("filter %s must be true or false, got %q", name, value)
+	}
+	switch name {
+	case VerifiedFilter:
+		s.Verified = &parsedValue
+	case BouncedFilter:
+		s.Bounced = &parsedValue
+	case ComplainedFilter:
+		s.Complained = &parsedValue
+	case UnsubscribedFilter:
+		s.Unsubscribed = &parsedValue
+	default:
+		return fmt.Errorf("unknown filter %q, expected %s, %s, %s or %s", name, VerifiedFilter, BouncedFilter, ComplainedFilter, UnsubscribedFilter)
+	}
+	return nil
+}
+
+func (s *EmailSubscriptionFilter) Matches(subscription *EmailSubscription) bool {
+	return filterMatches(s.Verified, subscription.Verified) &&
+		filterMatches(s.Bounced, subscription.HasBounce) &&
+		filterMatches(s.Complained, subscription.HasComplaint) &&
+		filterMatches(s.Unsubscribed, subscription.Unsubscribed)
+}
+
+func filterMatches(filter *bool, value bool) bool {
+	return filter == nil || *filter == value
+}
+
+// EmailSubscriptionPage is a page of a subscription listing
+type EmailSubscriptionPage struct {
+	Subscriptions []EmailSubscription
+	// NextCursor continues the listing after this page, and is empty on the last page. Pages may
+	// hold fewer subscriptions than requested, or none, before the last one.
+	NextCursor string
 }
@@ -0,0 +1,19 @@
+package models
+
+import "errors"
+
+var (
+	ErrInvalidSubscriptionToken  = errors.New("invalid subscription token")
+	ErrExpiredSubscriptionToken  = errors.New("expired subscription token")
+	ErrEmailSubscriptionNotFound = errors.New("email subscription not found")
+	ErrEmailSubscriptionExists   = errors.New("email subscription already exists")
+	ErrInvalidCursor             = errors.New("invalid pagination cursor")
+	ErrInvalidPageLimit          = errors.New("page limit must be positive")
+	ErrEmailAlreadyVerified      = errors.New("email subscription already verified")
+	ErrVerificationResendTooSoon = errors.New("verification email was sent too recently")
+	ErrUserNotFound              = errors.New("user not found")
+	ErrUserAlreadyExists         = errors.New("user already exists")
+	ErrEmailSubscriptionChanged  = errors.New("email subscription was changed concurrently")
+	ErrEventAlreadyProcessed     = errors.New("event was already processed")
+	ErrEventInProgress           = errors.New("event is being processed by another delivery")
+)
@@ -1,15 +1,21 @@
 package models
 
 type PlatformEvent struct {
+	EventId       string `json:"eventId"`
 	EventName     string `json:"eventType"`
 	CorrelationId string `json:"correlationId"`
 	EventDetails  string `json:"eventDetails"`
 }
 
+type AccountDeletedPlatformEvent struct {
+	EmailAddress string `json:"emailAddress"`
+}
+
 type EmailSendTaskPlatformEvent struct {
 	TemplateName  string            `json:"templateName"`
 	SenderAddress string            `json:"senderAddress"`
 	SubjectLine   string            `json:"subjectLine"`
 	ToAddresses   []string          `json:"toAddresses"`
 	Parameters    map[string]string `json:"parameters"`
+	Headers       map[string]string `json:"headers,omitempty"`
 }
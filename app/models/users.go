@@ -0,0 +1,12 @@
+package models
+
+type User struct {
+	Id              string `json:"id"`
+	Email           string `json:"email"`
+	CognitoUserName string `json:"cognito_user_name"`
+	CreatedAtUnix   int64  `json:"created_at"`
+	UpdatedAtUnix   int64  `json:"updated_at"`
+	// DeletedAtUnix is set when the user was soft deleted, and 0 otherwise. Soft deleted users no
+	// longer keep their email and Cognito user name.
+	DeletedAtUnix int64 `json:"deleted_at"`
+}
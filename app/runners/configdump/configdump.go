@@ -0,0 +1,48 @@
+package configdump
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"score/app/config"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// Run prints how every config parameter resolves, with secret values redacted. The configuration
+// is printed even when it's invalid, in which case the validation error is returned afterwards.
+var Run = func() error {
+	fmt.Println("Running score in config mode...")
+	awsSession := session.Must(session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	}))
+	configSources, err := config.SourcesFromEnvironment(awsSession)
+	if err != nil {
+		return fmt.Errorf("error initializing app config sources: %v", err.Error())
+	}
+	configService := config.New(config.AllParameterNames(), configSources...)
+	initializeErr := configService.InitializeParameters(context.Background())
+
+	if err := writeParameterStatuses(os.Stdout, configService.Describe()); err != nil {
+		return fmt.Errorf("error writing config: %v", err.Error())
+	}
+	if initializeErr != nil {
+		return fmt.Errorf("error initializing app config: %v", initializeErr.Error())
+	}
+	return nil
+}
+
+func writeParameterStatuses(w io.Writer, statuses []config.ParameterStatus) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "PARAMETER\tTYPE\tSOURCE\tRESOLVED\tVALUE\tPROBLEM")
+	for _, status := range statuses {
+		source := status.Source
+		if source == "" {
+			source = "-"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%t\t%s\t%s\n", status.Name, status.Type, source, status.Resolved, status.Value, status.Problem)
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,109 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"score/app/config"
+	"score/app/services/aws/dynamodb"
+	"score/app/services/aws/ses"
+	"score/app/services/aws/sns"
+	"score/app/services/relational"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// checkTimeout bounds how long a single dependency check may take
+const checkTimeout = 10 * time.Second
+
+// Parameters are the config parameters doctor mode loads
+var Parameters = append([]config.ConfigParameterName{
+	config.PlatformEventsSnsTopicArnParameterName,
+	config.EmailSubscriptionsTableNameParameterName,
+	config.ProcessedEventsTableNameParameterName,
+	config.MainTransactionalSendingAddressParameterName,
+}, config.MySQLParameters...)
+
+// check verifies that a dependency is reachable and usable
+type check struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// Run checks the connectivity to every dependency of the app and fails if any of them is unhealthy
+var Run = func() error {
+	fmt.Println("Running score in doctor mode...")
+	awsSession := session.Must(session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	}))
+	configSources, err := config.SourcesFromEnvironment(awsSession)
+	if err != nil {
+		return fmt.Errorf("error initializing app config sources: %v", err.Error())
+	}
+	configService := config.New(Parameters, configSources...)
+	err = configService.InitializeParameters(context.Background())
+	if err != nil {
+		return fmt.Errorf("error initializing app config: %v", err.Error())
+	}
+
+	dynamoDbService := dynamodb.New(awsSession, configService)
+	snsService := sns.New(awsSession, configService)
+	sesService := ses.New(awsSession)
+	relationalDB, err := relational.New(context.Background(), configService)
+	if err != nil {
+		return fmt.Errorf("error initializing relational database: %v", err.Error())
+	}
+	defer relationalDB.Close()
+
+	checks := []check{
+		{
+			name: "dynamodb email subscriptions table",
+			run: func(ctx context.Context) error {
+				return dynamoDbService.CheckTable(ctx, configService.EmailSubscriptionsTableName())
+			},
+		},
+		{
+			name: "dynamodb processed events table",
+			run: func(ctx context.Context) error {
+				return dynamoDbService.CheckTable(ctx, configService.ProcessedEventsTableName())
+			},
+		},
+		{
+			name: "sns platform events topic",
+			run:  snsService.CheckPlatformEventsTopic,
+		},
+		{
+			name: "relational database",
+			run:  relationalDB.Ping,
+		},
+		{
+			name: "ses sending identity",
+			run: func(ctx context.Context) error {
+				return sesService.CheckSendingIdentity(ctx, configService.MainTransactionalSendingAddress())
+			},
+		},
+	}
+	return runChecks(context.Background(), os.Stdout, checks)
+}
+
+// runChecks runs every check, reporting each result, and returns an error if any of them failed
+func runChecks(ctx context.Context, w io.Writer, checks []check) error {
+	failures := 0
+	for _, c := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		err := c.run(checkCtx)
+		cancel()
+		if err != nil {
+			failures++
+			fmt.Fprintf(w, "FAIL  %s: %v\n", c.name, err)
+		} else {
+			fmt.Fprintf(w, "OK    %s\n", c.name)
+		}
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d of %d dependency checks failed", failures, len(checks))
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunChecks(t *testing.T) {
+	ranChecks := []string{}
+	checks := []check{
+		{name: "healthy", run: func(ctx context.Context) error {
+			ranChecks = append(ranChecks, "healthy")
+			return nil
+		}},
+		{name: "unhealthy", run: func(ctx context.Context) error {
+			ranChecks = append(ranChecks, "unhealthy")
+			return errors.New("connection refused")
+		}},
+		{name: "after failure", run: func(ctx context.Context) error {
+			ranChecks = append(ranChecks, "after failure")
+			return nil
+		}},
+	}
+	output := &bytes.Buffer{}
+
+	err := runChecks(context.Background(), output, checks)
+	require.Error(t, err, "A failed check should fail the run")
+	require.Equal(t, []string{"healthy", "unhealthy", "after failure"}, ranChecks, "Every check should run even after a failure")
+	require.Equal(t, "OK    healthy\nFAIL  unhealthy: connection refused\nOK    after failure\n", output.String(), "Every result should be reported")
+}
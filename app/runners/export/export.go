@@ -0,0 +1,169 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"score/app/config"
+	"score/app/models"
+	"score/app/services/aws/dynamodb"
+	"score/app/services/datastore"
+	"score/app/services/email"
+	"score/app/services/memory"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// Formats of the export mode
+const (
+	CSVFormat       = "csv"
+	JSONLinesFormat = "jsonl"
+	DefaultFormat   = CSVFormat
+)
+
+// Parameters are the config parameters export mode loads
+var Parameters = []config.ConfigParameterName{
+	config.EmailSubscriptionsTableNameParameterName,
+	config.KeyValueStoreFileParameterName,
+}
+
+// csvHeader names the columns of the CSV export, in the order of csvRecord
+var csvHeader = []string{
+	"email",
+	"email_verified",
+	"creation_date",
+	"has_bounce",
+	"bounce_type",
+	"bounce_details",
+	"bounce_date",
+	"has_complaint",
+	"complaint_details",
+	"complaint_date",
+	"unsubscribed",
+	"unsubscribe_date",
+}
+
+// Run streams the email subscriptions matching the filters to stdout as CSV or JSON Lines. Filters
+// are name=value pairs such as verified=true or bounced=false.
+var Run = func(format string, filters []string) error {
+	// stdout carries the export, so progress goes to stderr
+	fmt.Fprintln(os.Stderr, "Running score in export mode...")
+	if format != CSVFormat && format != JSONLinesFormat {
+		return fmt.Errorf("unknown export format %q, expected %s or %s", format, CSVFormat, JSONLinesFormat)
+	}
+	filter, err := parseFilter(filters)
+	if err != nil {
+		return err
+	}
+	awsSession := session.Must(session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	}))
+	configSources, err := config.SourcesFromEnvironment(awsSession)
+	if err != nil {
+		return fmt.Errorf("error initializing app config sources: %v", err.Error())
+	}
+	configService := config.New(Parameters, configSources...)
+	err = configService.InitializeParameters(context.Background())
+	if err != nil {
+		return fmt.Errorf("error initializing app config: %v", err.Error())
+	}
+
+	var kvStore datastore.KeyValueStore = dynamodb.New(awsSession, configService)
+	if path := configService.KeyValueStoreFile(); path != "" {
+		kvStore, err = memory.NewFileKeyValueStore(path)
+		if err != nil {
+			return fmt.Errorf("error initializing key value store: %v", err.Error())
+		}
+	}
+	count, err := writeSubscriptions(context.Background(), os.Stdout, kvStore, format, filter)
+	if err != nil {
+		return fmt.Errorf("error exporting email subscriptions: %v", err.Error())
+	}
+	fmt.Fprintf(os.Stderr, "exported %d email subscriptions\n", count)
+	return nil
+}
+
+func parseFilter(filters []string) (models.EmailSubscriptionFilter, error) {
+	filter := models.EmailSubscriptionFilter{}
+	for _, expression := range filters {
+		name, value, ok := strings.Cut(expression, "=")
+		if !ok {
+			return filter, fmt.Errorf("filter %q is not a name=value pair", expression)
+		}
+		if err := filter.Set(name, value); err != nil {
+			return filter, err
+		}
+	}
+	return filter, nil
+}
+
+type subscriptionLister interface {
+	ListEmailSubscriptions(ctx context.Context, filter models.EmailSubscriptionFilter, cursor string, limit int) (*models.EmailSubscriptionPage, error)
+}
+
+// writeSubscriptions pages through the subscriptions and writes each page as it arrives, so that
+// the export never holds more than a page in memory. It returns the number of exported subscriptions.
+func writeSubscriptions(ctx context.Context, w io.Writer, lister subscriptionLister, format string, filter models.EmailSubscriptionFilter) (int, error) {
+	bufferedWriter := bufio.NewWriter(w)
+	csvWriter := csv.NewWriter(bufferedWriter)
+	jsonEncoder := json.NewEncoder(bufferedWriter)
+	if format == CSVFormat {
+		if err := csvWriter.Write(csvHeader); err != nil {
+			return 0, err
+		}
+	}
+
+	count := 0
+	cursor := ""
+	for {
+		page, err := lister.ListEmailSubscriptions(ctx, filter, cursor, email.MaxEmailSubscriptionPageSize)
+		if err != nil {
+			return count, err
+		}
+		for _, subscription := range page.Subscriptions {
+			summary := subscription.Summary()
+			if format == CSVFormat {
+				err = csvWriter.Write(csvRecord(summary))
+			} else {
+				err = jsonEncoder.Encode(summary)
+			}
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return count, err
+	}
+	return count, bufferedWriter.Flush()
+}
+
+func csvRecord(summary models.EmailSubscriptionSummary) []string {
+	return []string{
+		summary.Email,
+		strconv.FormatBool(summary.Verified),
+		strconv.FormatInt(summary.CreationDate, 10),
+		strconv.FormatBool(summary.HasBounce),
+		summary.BounceType,
+		summary.BounceDetails,
+		strconv.FormatInt(summary.BounceDateUnix, 10),
+		strconv.FormatBool(summary.HasComplaint),
+		summary.ComplaintDetails,
+		strconv.FormatInt(summary.ComplaintDateUnix, 10),
+		strconv.FormatBool(summary.Unsubscribed),
+		strconv.FormatInt(summary.UnsubscribeDateUnix, 10),
+	}
+}
@@ -0,0 +1,51 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"score/app/models"
+	"score/app/services/memory"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewKeyValueStore()
+	require.NoError(t, store.CreateEmailSubscriptionItem(ctx, "a@example.com", "secret-token", "secret-unsubscribe-token", 100, 200, true), "Creating a subscription should succeed")
+	require.NoError(t, store.CreateEmailSubscriptionItem(ctx, "b@example.com", "secret-token", "secret-unsubscribe-token", 100, 200, false), "Creating a subscription should succeed")
+
+	t.Run("csv", func(t *testing.T) {
+		var output bytes.Buffer
+		count, err := writeSubscriptions(ctx, &output, store, CSVFormat, models.EmailSubscriptionFilter{})
+		require.NoError(t, err, "Exporting should succeed")
+		require.Equal(t, 2, count, "Every subscription should be exported")
+		lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+		require.Len(t, lines, 3, "The export should have a header and a line per subscription")
+		require.Equal(t, strings.Join(csvHeader, ","), lines[0], "The first line should be the header")
+		require.True(t, strings.HasPrefix(lines[1], "a@example.com,true,"), "Rows should hold the subscription values")
+		require.NotContains(t, output.String(), "secret-", "Tokens should not be exported")
+	})
+
+	t.Run("jsonl with filter", func(t *testing.T) {
+		filter, err := parseFilter([]string{"verified=false"})
+		require.NoError(t, err, "Parsing the filter should succeed")
+		var output bytes.Buffer
+		count, err := writeSubscriptions(ctx, &output, store, JSONLinesFormat, filter)
+		require.NoError(t, err, "Exporting should succeed")
+		require.Equal(t, 1, count, "Only matching subscriptions should be exported")
+		var summary models.EmailSubscriptionSummary
+		require.NoError(t, json.Unmarshal(output.Bytes(), &summary), "Each line should be a JSON object")
+		require.Equal(t, "b@example.com", summary.Email, "The matching subscription should be exported")
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		_, err := parseFilter([]string{"verified"})
+		require.Error(t, err, "Filters must be name=value pairs")
+		_, err = parseFilter([]string{"spam=true"})
+		require.Error(t, err, "Unknown filters should be rejected")
+	})
+}
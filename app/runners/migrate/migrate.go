@@ -0,0 +1,111 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"score/app/config"
+	"score/app/services/migrations"
+	"score/app/services/relational"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// Commands of the migrate mode
+const (
+	UpCommand       = "up"
+	DownCommand     = "down"
+	StatusCommand   = "status"
+	BaselineCommand = "baseline"
+)
+
+// Parameters are the config parameters migrate mode loads
+var Parameters = config.MySQLParameters
+
+// Run applies the pending migrations (up), rolls back the latest one (down), lists them (status) or
+// marks the migrations of a database created before migrations were introduced as applied (baseline)
+var Run = func(command string) error {
+	fmt.Println("Running score in migrate mode...")
+	if command != UpCommand && command != DownCommand && command != StatusCommand && command != BaselineCommand {
+		return fmt.Errorf("unknown migrate command %q, expected %s, %s, %s or %s", command, UpCommand, DownCommand, StatusCommand, BaselineCommand)
+	}
+	awsSession := session.Must(session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	}))
+	configSources, err := config.SourcesFromEnvironment(awsSession)
+	if err != nil {
+		return fmt.Errorf("error initializing app config sources: %v", err.Error())
+	}
+	configService := config.New(Parameters, configSources...)
+	err = configService.InitializeParameters(context.Background())
+	if err != nil {
+		return fmt.Errorf("error initializing app config: %v", err.Error())
+	}
+
+	relationalDB, err := relational.New(context.Background(), configService)
+	if err != nil {
+		return fmt.Errorf("error initializing relational database: %v", err.Error())
+	}
+	defer relationalDB.Close()
+	migrator, err := migrations.New(relationalDB.DB())
+	if err != nil {
+		return err
+	}
+	return runCommand(context.Background(), os.Stdout, migrator, command)
+}
+
+func runCommand(ctx context.Context, w io.Writer, migrator *migrations.Migrator, command string) error {
+	switch command {
+	case UpCommand:
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Fprintf(w, "applied %d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(w, "no pending migrations")
+		}
+	case DownCommand:
+		migration, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if migration == nil {
+			fmt.Fprintln(w, "no applied migrations")
+		} else {
+			fmt.Fprintf(w, "rolled back %d_%s\n", migration.Version, migration.Name)
+		}
+	case BaselineCommand:
+		recorded, err := migrator.Baseline(ctx)
+		for _, migration := range recorded {
+			fmt.Fprintf(w, "marked %d_%s as applied\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(recorded) == 0 {
+			fmt.Fprintln(w, "baseline migrations already applied")
+		}
+	case StatusCommand:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = time.Unix(status.AppliedAtUnix, 0).UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(writer, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return writer.Flush()
+	}
+	return nil
+}
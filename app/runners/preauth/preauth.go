@@ -5,6 +5,6 @@ import (
 )
 
 var Run = func() error {
-	fmt.Println("Running account confirmation handler...")
+	fmt.Println("Running pre authentication handler...")
 	return nil
 }
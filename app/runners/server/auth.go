@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"score/app/runners/server/handler"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+// jwksMinRefreshInterval bounds how often the cached JWK set is re-fetched, even when the
+// user pool responds with short cache headers or tokens reference unknown key IDs
+const jwksMinRefreshInterval = 15 * time.Minute
+
+// jwtAuthenticator validates Cognito issued tokens against a cached, periodically refreshed JWK set
+type jwtAuthenticator struct {
+	config  Config
+	keySets *jwk.AutoRefresh
+}
+
+func newJwtAuthenticator(ctx context.Context, config Config) *jwtAuthenticator {
+	keySets := jwk.NewAutoRefresh(ctx)
+	keySets.Configure(config.CognitoJwksUrl(), jwk.WithMinRefreshInterval(jwksMinRefreshInterval))
+	return &jwtAuthenticator{
+		config:  config,
+		keySets: keySets,
+	}
+}
+
+func (s *jwtAuthenticator) authenticate(ctx context.Context, authHeader string) (*handler.AuthClaims, error) {
+	const BearerSchema = "Bearer "
+	if authHeader == "" {
+		return nil, fmt.Errorf("no auth header")
+	}
+	if !strings.HasPrefix(authHeader, BearerSchema) {
+		return nil, fmt.Errorf("auth header is not a bearer token")
+	}
+
+	tokenString := strings.TrimSpace(strings.TrimPrefix(authHeader, BearerSchema))
+	if tokenString == "" {
+		return nil, fmt.Errorf("no token string")
+	}
+
+	jwkSet, err := s.keySets.Fetch(ctx, s.config.CognitoJwksUrl())
+	if err != nil {
+		return nil, fmt.Errorf("error while fetching JWK set => %v", err.Error())
+	}
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		keyID, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid key ID")
+		}
+		jwtKey, ok := jwkSet.LookupKeyID(keyID)
+		if !ok {
+			return nil, fmt.Errorf("no key found with ID: %s", keyID)
+		}
+		var tokenKey interface{}
+		if err := jwtKey.Raw(&tokenKey); err != nil {
+			return nil, fmt.Errorf("error while getting raw token key: %s", err)
+		}
+		return tokenKey, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parsed token is not valid => %v", err.Error())
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("parsed token is not valid")
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("claims are not of type MapClaims")
+	}
+	if err := s.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return parseAuthClaims(claims), nil
+}
+
+// validateClaims checks the claims that the signature alone doesn't guarantee, following
+// https://docs.aws.amazon.com/cognito/latest/developerguide/amazon-cognito-user-pools-using-tokens-verifying-a-jwt.html
+func (s *jwtAuthenticator) validateClaims(claims jwt.MapClaims) error {
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return fmt.Errorf("token is expired or has no expiry")
+	}
+	if !claims.VerifyIssuer(s.config.CognitoIssuer(), true) {
+		return fmt.Errorf("unexpected token issuer: %v", claims["iss"])
+	}
+	tokenUse, _ := claims["token_use"].(string)
+	switch tokenUse {
+	case "id":
+		if !claims.VerifyAudience(s.config.CognitoAppClientId(), true) {
+			return fmt.Errorf("unexpected token audience: %v", claims["aud"])
+		}
+	case "access":
+		clientId, _ := claims["client_id"].(string)
+		if clientId != s.config.CognitoAppClientId() {
+			return fmt.Errorf("unexpected token client id: %v", clientId)
+		}
+	default:
+		return fmt.Errorf("unexpected token use: %v", claims["token_use"])
+	}
+	return nil
+}
+
+// parseAuthClaims extracts the claims handlers care about. ID tokens and access tokens name the
+// username claim differently, and only ID tokens carry the email address.
+func parseAuthClaims(claims jwt.MapClaims) *handler.AuthClaims {
+	authClaims := &handler.AuthClaims{}
+	authClaims.Subject, _ = claims["sub"].(string)
+	authClaims.Email, _ = claims["email"].(string)
+	if username, ok := claims["cognito:username"].(string); ok {
+		authClaims.Username = username
+	} else {
+		authClaims.Username, _ = claims["username"].(string)
+	}
+	if groups, ok := claims["cognito:groups"].([]interface{}); ok {
+		for _, group := range groups {
+			if groupName, ok := group.(string); ok {
+				authClaims.Groups = append(authClaims.Groups, groupName)
+			}
+		}
+	}
+	return authClaims
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"score/app/logger"
+	"score/app/runners/server/handler"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIssuer   = "https://cognito-idp.us-east-1.amazonaws.com/us-east-1_test"
+	testClientId = "client-1"
+	testKeyId    = "key-1"
+)
+
+type testConfig struct {
+	jwksUrl string
+}
+
+func (c *testConfig) CognitoIssuer() string      { return testIssuer }
+func (c *testConfig) CognitoJwksUrl() string     { return c.jwksUrl }
+func (c *testConfig) CognitoAppClientId() string { return testClientId }
+func (c *testConfig) AdminGroup() string         { return "admin" }
+
+// validIdClaims returns the claims of an ID token the authenticator accepts
+func validIdClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":              testIssuer,
+		"aud":              testClientId,
+		"token_use":        "id",
+		"exp":              float64(time.Now().Add(time.Hour).Unix()),
+		"sub":              "subject-1",
+		"email":            "a@example.com",
+		"cognito:username": "user-1",
+		"cognito:groups":   []interface{}{"admin"},
+	}
+}
+
+func TestValidateClaims(t *testing.T) {
+	authenticator := &jwtAuthenticator{config: &testConfig{}}
+	tests := []struct {
+		name    string
+		modify  func(claims jwt.MapClaims)
+		wantErr bool
+	}{
+		{name: "valid id token", modify: func(claims jwt.MapClaims) {}},
+		{name: "valid access token", modify: func(claims jwt.MapClaims) {
+			claims["token_use"] = "access"
+			claims["client_id"] = testClientId
+			delete(claims, "aud")
+		}},
+		{name: "missing expiry", modify: func(claims jwt.MapClaims) { delete(claims, "exp") }, wantErr: true},
+		{name: "expired", modify: func(claims jwt.MapClaims) { claims["exp"] = float64(time.Now().Add(-time.Minute).Unix()) }, wantErr: true},
+		{name: "missing issuer", modify: func(claims jwt.MapClaims) { delete(claims, "iss") }, wantErr: true},
+		{name: "other issuer", modify: func(claims jwt.MapClaims) { claims["iss"] = "https://example.com" }, wantErr: true},
+		{name: "id token for another client", modify: func(claims jwt.MapClaims) { claims["aud"] = "client-2" }, wantErr: true},
+		{name: "id token without audience", modify: func(claims jwt.MapClaims) { delete(claims, "aud") }, wantErr: true},
+		{name: "access token for another client", modify: func(claims jwt.MapClaims) {
+			claims["token_use"] = "access"
+			claims["client_id"] = "client-2"
+		}, wantErr: true},
+		{name: "access token without client id", modify: func(claims jwt.MapClaims) { claims["token_use"] = "access" }, wantErr: true},
+		{name: "missing token use", modify: func(claims jwt.MapClaims) { delete(claims, "token_use") }, wantErr: true},
+		{name: "unknown token use", modify: func(claims jwt.MapClaims) { claims["token_use"] = "refresh" }, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims := validIdClaims()
+			test.modify(claims)
+			err := authenticator.validateClaims(claims)
+			if test.wantErr {
+				require.Error(t, err, "The claims should be rejected")
+			} else {
+				require.NoError(t, err, "The claims should be accepted")
+			}
+		})
+	}
+}
+
+func TestRequireAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "Generating a signing key should succeed")
+	publicKey, err := jwk.New(&privateKey.PublicKey)
+	require.NoError(t, err, "Creating the public JWK should succeed")
+	require.NoError(t, publicKey.Set(jwk.KeyIDKey, testKeyId), "Setting the key ID should succeed")
+	keySet := jwk.NewSet()
+	keySet.Add(publicKey)
+	jwks, err := json.Marshal(keySet)
+	require.NoError(t, err, "Encoding the JWK set should succeed")
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jwks)
+	}))
+	defer jwksServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	router := &Router{logger: logger.NewNop(), authenticator: newJwtAuthenticator(ctx, &testConfig{jwksUrl: jwksServer.URL})}
+	var claims *handler.AuthClaims
+	engine := gin.New()
+	engine.GET("/private", router.RequireAuthentication(), func(c *gin.Context) {
+		value, _ := c.Get(handler.AuthClaimsKey)
+		claims, _ = value.(*handler.AuthClaims)
+		c.Status(http.StatusOK)
+	})
+	sign := func(claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = testKeyId
+		signed, err := token.SignedString(privateKey)
+		require.NoError(t, err, "Signing a token should succeed")
+		return signed
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "Generating another signing key should succeed")
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, validIdClaims())
+	forged.Header["kid"] = testKeyId
+	forgedToken, err := forged.SignedString(otherKey)
+	require.NoError(t, err, "Signing a forged token should succeed")
+
+	tests := []struct {
+		name           string
+		header         string
+		expectedStatus int
+	}{
+		{name: "missing header", header: "", expectedStatus: http.StatusUnauthorized},
+		{name: "not a bearer token", header: "Basic dXNlcjpwYXNz", expectedStatus: http.StatusUnauthorized},
+		{name: "empty bearer token", header: "Bearer ", expectedStatus: http.StatusUnauthorized},
+		{name: "malformed token", header: "Bearer not.a.token", expectedStatus: http.StatusUnauthorized},
+		{name: "forged signature", header: "Bearer " + forgedToken, expectedStatus: http.StatusUnauthorized},
+		{name: "valid token", header: "Bearer " + sign(validIdClaims()), expectedStatus: http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims = nil
+			request := httptest.NewRequest(http.MethodGet, "/private", nil)
+			if test.header != "" {
+				request.Header.Set("Authorization", test.header)
+			}
+			recorder := httptest.NewRecorder()
+			engine.ServeHTTP(recorder, request)
+			require.Equal(t, test.expectedStatus, recorder.Code, "The status should reflect the authorization header")
+			if test.expectedStatus == http.StatusOK {
+				require.NotNil(t, claims, "The claims should be stored on the context")
+				require.Equal(t, "a@example.com", claims.Email, "The email claim should be parsed")
+				require.Equal(t, "user-1", claims.Username, "The username claim should be parsed")
+				require.Equal(t, []string{"admin"}, claims.Groups, "The groups claim should be parsed")
+			} else {
+				require.Nil(t, claims, "Rejected requests should not reach the handler")
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package handler
+
+import "github.com/gin-gonic/gin"
+
+// AuthClaimsKey is the gin context key holding the verified claims of the authenticated user
+const AuthClaimsKey = "authClaims"
+
+// AuthClaims are the verified token claims of the authenticated user
+type AuthClaims struct {
+	Subject  string
+	Email    string
+	Username string
+	Groups   []string
+}
+
+func getAuthClaims(c *gin.Context) (*AuthClaims, bool) {
+	value, exists := c.Get(AuthClaimsKey)
+	if !exists {
+		return nil, false
+	}
+	claims, ok := value.(*AuthClaims)
+	return claims, ok
+}
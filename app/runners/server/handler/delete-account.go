@@ -1,12 +1,43 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"score/app/logger"
+	"score/app/models"
 
 	"github.com/gin-gonic/gin"
 )
 
+// DeleteAccountHandler deletes the account of the authenticated user. The user is identified by the
+// Cognito user name rather than the email claim, which users can change to an unverified address.
 func (s *RouteHandler) DeleteAccountHandler(c *gin.Context) {
+	requestLogger := logger.FromContext(c.Request.Context(), s.loggerService)
+	claims, ok := getAuthClaims(c)
+	if !ok || claims.Username == "" {
+		requestLogger.ErrorWithContext("no authenticated user name found while deleting account")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userName := claims.Username
+	err := s.userService.DeleteAccount(c.Request.Context(), userName)
+	if err != nil {
+		requestLogger.ErrorWithContext(
+			"error while deleting account",
+			"userName", userName,
+			"error", err.Error(),
+		)
+		if errors.Is(err, models.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while deleting your account"})
+		return
+	}
+	requestLogger.InfoWithContext(
+		"Successfully deleted account",
+		"userName", userName,
+	)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "account successfully deleted",
 	})
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"score/app/logger"
+	"score/app/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUserService records the user names of the deleted accounts and returns err
+type fakeUserService struct {
+	err       error
+	userNames []string
+}
+
+func (s *fakeUserService) DeleteAccount(ctx context.Context, cognitoUserName string) error {
+	s.userNames = append(s.userNames, cognitoUserName)
+	return s.err
+}
+
+func TestDeleteAccountHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name           string
+		claims         *AuthClaims
+		err            error
+		expectedStatus int
+	}{
+		{name: "deleted", claims: &AuthClaims{Username: "cognito-a", Email: "b@example.com"}, expectedStatus: http.StatusOK},
+		{name: "unauthenticated", claims: nil, expectedStatus: http.StatusUnauthorized},
+		{name: "no user name", claims: &AuthClaims{Email: "b@example.com"}, expectedStatus: http.StatusUnauthorized},
+		{name: "missing account", claims: &AuthClaims{Username: "cognito-a"}, err: fmt.Errorf("%w: cognito-a", models.ErrUserNotFound), expectedStatus: http.StatusNotFound},
+		{name: "failure", claims: &AuthClaims{Username: "cognito-a"}, err: fmt.Errorf("connection reset"), expectedStatus: http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			userService := &fakeUserService{err: test.err}
+			routeHandler := New(&fakeEmailService{}, userService, logger.NewNop())
+			engine := gin.New()
+			engine.DELETE("/account", func(c *gin.Context) {
+				if test.claims != nil {
+					c.Set(AuthClaimsKey, test.claims)
+				}
+				c.Next()
+			}, routeHandler.DeleteAccountHandler)
+			recorder := httptest.NewRecorder()
+			engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/account", nil))
+			require.Equal(t, test.expectedStatus, recorder.Code, "The status should reflect the deletion")
+			if test.claims != nil && test.claims.Username != "" {
+				require.Equal(t, []string{test.claims.Username}, userService.userNames, "The account should be looked up by Cognito user name")
+			} else {
+				require.Empty(t, userService.userNames, "Requests without a user name should not delete anything")
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"score/app/logger"
+	"score/app/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAdminEmailSubscriptionsHandler lists the email subscriptions a page at a time. The verified,
+// bounced, complained and unsubscribed query parameters filter the listing, limit sets the page size,
+// and cursor continues from the next_cursor of the previous page.
+func (s *RouteHandler) GetAdminEmailSubscriptionsHandler(c *gin.Context) {
+	requestLogger := logger.FromContext(c.Request.Context(), s.loggerService)
+	filter := models.EmailSubscriptionFilter{}
+	for _, name := range []string{models.VerifiedFilter, models.BouncedFilter, models.ComplainedFilter, models.UnsubscribedFilter} {
+		value, ok := c.GetQuery(name)
+		if !ok {
+			continue
+		}
+		if err := filter.Set(name, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	limit := 0
+	if value, ok := c.GetQuery("limit"); ok {
+		parsedLimit, err := strconv.Atoi(value)
+		if err != nil || parsedLimit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = parsedLimit
+	}
+
+	page, err := s.emailService.ListEmailSubscriptions(c.Request.Context(), filter, c.Query("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		requestLogger.ErrorWithContext(
+			"error while listing email subscriptions",
+			"error", err.Error(),
+		)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while listing email subscriptions"})
+		return
+	}
+	subscriptions := make([]models.EmailSubscriptionSummary, 0, len(page.Subscriptions))
+	for _, subscription := range page.Subscriptions {
+		subscriptions = append(subscriptions, subscription.Summary())
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"subscriptions": subscriptions,
+		"next_cursor":   page.NextCursor,
+	})
+}
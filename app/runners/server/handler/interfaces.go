@@ -1,22 +1,35 @@
 package handler
 
+import (
+	"context"
+	"score/app/models"
+)
+
 type RouteHandler struct {
 	emailService  EmailService
+	userService   UserService
 	loggerService LoggerService
 }
 
-func New(emailService EmailService, loggerService LoggerService) *RouteHandler {
+func New(emailService EmailService, userService UserService, loggerService LoggerService) *RouteHandler {
 	return &RouteHandler{
 		emailService:  emailService,
+		userService:   userService,
 		loggerService: loggerService,
 	}
 }
 
 type EmailService interface {
-	CreateEmailSubscription(email string) error
-	EmailSubscriptionExists(email string) (bool, error)
+	CreateEmailSubscription(ctx context.Context, email string) error
 	IsValidEmail(email string) bool
-	VerifyEmailWithSubscriptionToken(token string) error
+	VerifyEmailWithSubscriptionToken(ctx context.Context, token string) error
+	ResendVerificationEmail(ctx context.Context, email string) error
+	UnsubscribeWithToken(ctx context.Context, token string) error
+	ListEmailSubscriptions(ctx context.Context, filter models.EmailSubscriptionFilter, cursor string, limit int) (*models.EmailSubscriptionPage, error)
+}
+
+type UserService interface {
+	DeleteAccount(ctx context.Context, cognitoUserName string) error
 }
 
 type LoggerService interface {
@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"score/app/logger"
+	"score/app/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,9 +14,10 @@ type PostEmailSubscriptionRequestData struct {
 }
 
 func (s *RouteHandler) PostEmailSubscriptionsHandler(c *gin.Context) {
+	requestLogger := logger.FromContext(c.Request.Context(), s.loggerService)
 	var data PostEmailSubscriptionRequestData
 	if err := c.ShouldBindJSON(&data); err != nil {
-		s.loggerService.ErrorWithContext(
+		requestLogger.ErrorWithContext(
 			"error while parsing PostEmailSubscriptionRequestData",
 			"error", err.Error(),
 		)
@@ -22,40 +26,32 @@ func (s *RouteHandler) PostEmailSubscriptionsHandler(c *gin.Context) {
 	}
 
 	if !s.emailService.IsValidEmail(data.EmailAddress) {
-		s.loggerService.ErrorWithContext(
+		requestLogger.ErrorWithContext(
 			"invalid email address",
 			"email", data.EmailAddress,
 		)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email address"})
 		return
 	}
-	subscriptionExists, err := s.emailService.EmailSubscriptionExists(data.EmailAddress)
-	if err != nil {
-		s.loggerService.ErrorWithContext(
-			"error while checking if email subscription already exists",
+	// Creating the subscription fails when it already exists, which keeps concurrent sign-ups from
+	// overwriting each other's token
+	err := s.emailService.CreateEmailSubscription(c.Request.Context(), data.EmailAddress)
+	switch {
+	case errors.Is(err, models.ErrEmailSubscriptionExists):
+		requestLogger.InfoWithContext(
+			"Email subscription already exists",
+			"email", data.EmailAddress,
+		)
+	case err != nil:
+		requestLogger.ErrorWithContext(
+			"error while creating email subscription",
 			"error", err.Error(),
 		)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while processing your email subscription"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while saving your email subscription"})
 		return
-	}
-
-	if !subscriptionExists {
-		if err := s.emailService.CreateEmailSubscription(data.EmailAddress); err != nil {
-			s.loggerService.ErrorWithContext(
-				"error while creating email subscription",
-				"error", err.Error(),
-			)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while saving your email subscription"})
-			return
-		} else {
-			s.loggerService.InfoWithContext(
-				"Successfully created email subscription",
-				"email", data.EmailAddress,
-			)
-		}
-	} else {
-		s.loggerService.InfoWithContext(
-			"Email subscription already exists",
+	default:
+		requestLogger.InfoWithContext(
+			"Successfully created email subscription",
 			"email", data.EmailAddress,
 		)
 	}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"score/app/logger"
+	"score/app/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PostResendVerificationEmailRequestData struct {
+	EmailAddress string `json:"email"`
+}
+
+func (s *RouteHandler) PostResendVerificationEmailHandler(c *gin.Context) {
+	requestLogger := logger.FromContext(c.Request.Context(), s.loggerService)
+	var data PostResendVerificationEmailRequestData
+	if err := c.ShouldBindJSON(&data); err != nil {
+		requestLogger.ErrorWithContext(
+			"error while parsing PostResendVerificationEmailRequestData",
+			"error", err.Error(),
+		)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !s.emailService.IsValidEmail(data.EmailAddress) {
+		requestLogger.ErrorWithContext(
+			"invalid email address",
+			"email", data.EmailAddress,
+		)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email address"})
+		return
+	}
+
+	err := s.emailService.ResendVerificationEmail(c.Request.Context(), data.EmailAddress)
+	switch {
+	case err == nil:
+		requestLogger.InfoWithContext(
+			"Successfully resent verification email",
+			"email", data.EmailAddress,
+		)
+	case errors.Is(err, models.ErrVerificationResendTooSoon):
+		requestLogger.InfoWithContext(
+			"Verification email resend requested during cooldown",
+			"email", data.EmailAddress,
+		)
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "a verification email was sent recently, please try again later"})
+		return
+	case errors.Is(err, models.ErrEmailSubscriptionNotFound), errors.Is(err, models.ErrEmailAlreadyVerified):
+		// Respond the same way as a successful resend so that the endpoint can't be used to discover subscriptions
+		requestLogger.InfoWithContext(
+			"Verification email resend skipped",
+			"email", data.EmailAddress,
+			"reason", err.Error(),
+		)
+	default:
+		requestLogger.ErrorWithContext(
+			"error while resending verification email",
+			"error", err.Error(),
+		)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while resending your verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "verification email resent",
+		"email":   data.EmailAddress,
+	})
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"score/app/logger"
+	"score/app/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PostUnsubscribeRequestData struct {
+	UnsubscribeToken string `json:"token"`
+}
+
+func (s *RouteHandler) PostUnsubscribeHandler(c *gin.Context) {
+	requestLogger := logger.FromContext(c.Request.Context(), s.loggerService)
+	var data PostUnsubscribeRequestData
+	if err := c.ShouldBindJSON(&data); err != nil {
+		requestLogger.ErrorWithContext(
+			"error while parsing PostUnsubscribeHandler request",
+			"error", err.Error(),
+		)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	unescapedUnsubscribeToken, err := url.QueryUnescape(data.UnsubscribeToken)
+	if err != nil {
+		requestLogger.ErrorWithContext(
+			"error while unescaping unsubscribe token",
+			"error", err.Error(),
+		)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error while parsing unsubscribe token"})
+		return
+	}
+	s.unsubscribe(c, unescapedUnsubscribeToken)
+}
+
+// PostOneClickUnsubscribeHandler handles RFC 8058 one-click unsubscribe requests sent by mail clients
+// to the URL in the List-Unsubscribe header
+func (s *RouteHandler) PostOneClickUnsubscribeHandler(c *gin.Context) {
+	requestLogger := logger.FromContext(c.Request.Context(), s.loggerService)
+	if c.PostForm("List-Unsubscribe") != "One-Click" {
+		requestLogger.ErrorWithContext("one-click unsubscribe request is missing List-Unsubscribe=One-Click")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid one-click unsubscribe request"})
+		return
+	}
+	s.unsubscribe(c, c.Query("token"))
+}
+
+func (s *RouteHandler) unsubscribe(c *gin.Context, unsubscribeToken string) {
+	requestLogger := logger.FromContext(c.Request.Context(), s.loggerService)
+	err := s.emailService.UnsubscribeWithToken(c.Request.Context(), unsubscribeToken)
+	if err != nil {
+		requestLogger.ErrorWithContext(
+			"error while unsubscribing email",
+			"error", err.Error(),
+		)
+		switch {
+		// Unknown emails get the same response as invalid tokens so that the endpoint can't be used
+		// to discover subscriptions
+		case errors.Is(err, models.ErrInvalidSubscriptionToken), errors.Is(err, models.ErrEmailSubscriptionNotFound):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "invalid unsubscribe token",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "error while attempting to unsubscribe",
+			})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "unsubscribe successful",
+	})
+}
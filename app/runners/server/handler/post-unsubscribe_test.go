@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"score/app/logger"
+	"score/app/models"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostOneClickUnsubscribeHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name           string
+		body           string
+		err            error
+		expectedStatus int
+	}{
+		{name: "unsubscribed", body: "List-Unsubscribe=One-Click", expectedStatus: http.StatusOK},
+		{name: "missing one-click body", body: "", expectedStatus: http.StatusBadRequest},
+		{name: "wrong one-click body", body: "List-Unsubscribe=Yes", expectedStatus: http.StatusBadRequest},
+		{name: "invalid token", body: "List-Unsubscribe=One-Click", err: fmt.Errorf("%w: forged", models.ErrInvalidSubscriptionToken), expectedStatus: http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			routeHandler := New(&fakeEmailService{err: test.err}, nil, logger.NewNop())
+			engine := gin.New()
+			engine.POST("/one-click", routeHandler.PostOneClickUnsubscribeHandler)
+			request := httptest.NewRequest(http.MethodPost, "/one-click?token=dG9rZW4%3D%3Aabc", strings.NewReader(test.body))
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			recorder := httptest.NewRecorder()
+			engine.ServeHTTP(recorder, request)
+			require.Equal(t, test.expectedStatus, recorder.Code, "The status should reflect the one-click request")
+		})
+	}
+}
+
+func TestPostUnsubscribeHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	respond := func(err error) *httptest.ResponseRecorder {
+		routeHandler := New(&fakeEmailService{err: err}, nil, logger.NewNop())
+		engine := gin.New()
+		engine.POST("/unsubscribe", routeHandler.PostUnsubscribeHandler)
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/unsubscribe", strings.NewReader(`{"token":"dG9rZW4=:abc"}`)))
+		return recorder
+	}
+
+	require.Equal(t, http.StatusOK, respond(nil).Code, "A valid token should unsubscribe")
+	require.Equal(t, http.StatusInternalServerError, respond(errors.New("connection reset")).Code, "Datastore failures should be reported")
+
+	invalidToken := respond(fmt.Errorf("%w: forged", models.ErrInvalidSubscriptionToken))
+	missingSubscription := respond(fmt.Errorf("%w: a@example.com", models.ErrEmailSubscriptionNotFound))
+	require.Equal(t, http.StatusBadRequest, invalidToken.Code, "An invalid token should be rejected")
+	require.Equal(t, invalidToken.Code, missingSubscription.Code, "Unknown emails should get the same status as invalid tokens")
+	require.Equal(t, invalidToken.Body.String(), missingSubscription.Body.String(), "Unknown emails should get the same body as invalid tokens")
+}
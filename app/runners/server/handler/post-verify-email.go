@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"net/url"
+	"score/app/logger"
+	"score/app/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,9 +15,10 @@ type PostVerifyEmailRequestData struct {
 }
 
 func (s *RouteHandler) PostVerifyEmailHandler(c *gin.Context) {
+	requestLogger := logger.FromContext(c.Request.Context(), s.loggerService)
 	var data PostVerifyEmailRequestData
 	if err := c.ShouldBindJSON(&data); err != nil {
-		s.loggerService.ErrorWithContext(
+		requestLogger.ErrorWithContext(
 			"error while parsing PostVerifyEmailHandler request",
 			"error", err.Error(),
 		)
@@ -23,7 +27,7 @@ func (s *RouteHandler) PostVerifyEmailHandler(c *gin.Context) {
 	}
 	unescapedSubscriptionToken, err := url.QueryUnescape(data.SubscriptionToken)
 	if err != nil {
-		s.loggerService.ErrorWithContext(
+		requestLogger.ErrorWithContext(
 			"error while unescaping email verification token",
 			"error", err.Error(),
 			"token", data.SubscriptionToken,
@@ -31,16 +35,31 @@ func (s *RouteHandler) PostVerifyEmailHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "error while parsing verification token"})
 		return
 	}
-	err = s.emailService.VerifyEmailWithSubscriptionToken(unescapedSubscriptionToken)
+	err = s.emailService.VerifyEmailWithSubscriptionToken(c.Request.Context(), unescapedSubscriptionToken)
 	if err != nil {
-		s.loggerService.ErrorWithContext(
+		requestLogger.ErrorWithContext(
 			"error while verifying email",
 			"error", err.Error(),
 			"token", unescapedSubscriptionToken,
 		)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": "error while attempting to verify subscription",
-		})
+		switch {
+		case errors.Is(err, models.ErrInvalidSubscriptionToken):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "invalid verification token",
+			})
+		case errors.Is(err, models.ErrExpiredSubscriptionToken):
+			c.JSON(http.StatusGone, gin.H{
+				"message": "verification token has expired, request a new verification email",
+			})
+		case errors.Is(err, models.ErrEmailSubscriptionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "email subscription not found",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "error while attempting to verify subscription",
+			})
+		}
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
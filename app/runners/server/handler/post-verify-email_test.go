@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"score/app/logger"
+	"score/app/models"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEmailService returns err from every call
+type fakeEmailService struct {
+	err error
+}
+
+func (s *fakeEmailService) CreateEmailSubscription(ctx context.Context, email string) error {
+	return s.err
+}
+
+func (s *fakeEmailService) IsValidEmail(email string) bool {
+	return true
+}
+
+func (s *fakeEmailService) VerifyEmailWithSubscriptionToken(ctx context.Context, token string) error {
+	return s.err
+}
+
+func (s *fakeEmailService) ResendVerificationEmail(ctx context.Context, email string) error {
+	return s.err
+}
+
+func (s *fakeEmailService) UnsubscribeWithToken(ctx context.Context, token string) error {
+	return s.err
+}
+
+func (s *fakeEmailService) ListEmailSubscriptions(ctx context.Context, filter models.EmailSubscriptionFilter, cursor string, limit int) (*models.EmailSubscriptionPage, error) {
+	return &models.EmailSubscriptionPage{}, s.err
+}
+
+func TestPostVerifyEmailHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{name: "verified", err: nil, expectedStatus: http.StatusOK},
+		{name: "invalid token", err: fmt.Errorf("%w: forged", models.ErrInvalidSubscriptionToken), expectedStatus: http.StatusBadRequest},
+		{name: "expired token", err: fmt.Errorf("%w: expired", models.ErrExpiredSubscriptionToken), expectedStatus: http.StatusGone},
+		{name: "missing subscription", err: fmt.Errorf("%w: a@example.com", models.ErrEmailSubscriptionNotFound), expectedStatus: http.StatusNotFound},
+		{name: "datastore failure", err: errors.New("connection reset"), expectedStatus: http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			routeHandler := New(&fakeEmailService{err: test.err}, nil, logger.NewNop())
+			engine := gin.New()
+			engine.POST("/verify", routeHandler.PostVerifyEmailHandler)
+			recorder := httptest.NewRecorder()
+			engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/verify", strings.NewReader(`{"token":"dG9rZW4=:abc"}`)))
+			require.Equal(t, test.expectedStatus, recorder.Code, "The status should reflect the verification error")
+		})
+	}
+
+	t.Run("malformed body", func(t *testing.T) {
+		routeHandler := New(&fakeEmailService{}, nil, logger.NewNop())
+		engine := gin.New()
+		engine.POST("/verify", routeHandler.PostVerifyEmailHandler)
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/verify", strings.NewReader(`not json`)))
+		require.Equal(t, http.StatusBadRequest, recorder.Code, "A malformed body should be rejected")
+	})
+}
@@ -2,26 +2,34 @@ package server
 
 import (
 	"context"
-	"fmt"
 	"net/http"
+	"score/app/correlation"
+	"score/app/logger"
+	"score/app/runners/server/handler"
+	"time"
 
-	"strings"
-
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
-	"github.com/lestrrat-go/jwx/jwk"
 )
 
+// requestTimeout bounds how long a request may spend calling downstream services
+const requestTimeout = 10 * time.Second
+
 type Router struct {
 	handler                 RouteHandler
 	config                  Config
+	logger                  Logger
+	authenticator           *jwtAuthenticator
 	webAppFileSystemHandler http.Handler
 }
 
-func New(handler RouteHandler, config Config, webAppFileSystemHandler http.Handler) *Router {
+// New creates the router. The JWK set used to authenticate requests is refreshed in the background
+// until ctx is done.
+func New(ctx context.Context, handler RouteHandler, config Config, logger Logger, webAppFileSystemHandler http.Handler) *Router {
 	return &Router{
 		handler:                 handler,
 		config:                  config,
+		logger:                  logger,
+		authenticator:           newJwtAuthenticator(ctx, config),
 		webAppFileSystemHandler: webAppFileSystemHandler,
 	}
 }
@@ -29,21 +37,43 @@ func New(handler RouteHandler, config Config, webAppFileSystemHandler http.Handl
 type RouteHandler interface {
 	PostEmailSubscriptionsHandler(c *gin.Context)
 	PostVerifyEmailHandler(c *gin.Context)
+	PostResendVerificationEmailHandler(c *gin.Context)
+	PostUnsubscribeHandler(c *gin.Context)
+	PostOneClickUnsubscribeHandler(c *gin.Context)
 	DeleteAccountHandler(c *gin.Context)
+	GetAdminEmailSubscriptionsHandler(c *gin.Context)
 }
 
 type Config interface {
-	CorsAllowedOrigins() string
+	CognitoIssuer() string
+	CognitoJwksUrl() string
+	CognitoAppClientId() string
+	AdminGroup() string
+}
+
+type Logger interface {
+	InfoWithContext(message string, keysAndValues ...interface{})
+	ErrorWithContext(message string, keysAndValues ...interface{})
+	DebugWithContext(message string, keysAndValues ...interface{})
+	With(keysAndValues ...interface{}) logger.Interface
 }
 
 func (s *Router) GetRouter() *gin.Engine {
-	r := gin.Default()
+	r := gin.New()
+	r.Use(gin.Recovery(), s.RequestID(), s.AccessLog())
 
 	v1 := r.Group("/v1")
+	v1.Use(s.RequestTimeout(requestTimeout))
 	{
 		v1.POST("/email-subscriptions", s.handler.PostEmailSubscriptionsHandler)
+		v1.POST("/email-subscriptions/unsubscribe", s.handler.PostUnsubscribeHandler)
+		v1.POST("/email-subscriptions/unsubscribe/one-click", s.handler.PostOneClickUnsubscribeHandler)
 		v1.POST("/email-verifications", s.handler.PostVerifyEmailHandler)
-		v1.DELETE("/account", s.handler.DeleteAccountHandler)
+		v1.POST("/email-verifications/resend", s.handler.PostResendVerificationEmailHandler)
+		v1.DELETE("/account", s.RequireAuthentication(), s.handler.DeleteAccountHandler)
+
+		admin := v1.Group("/admin", s.RequireAuthentication(), s.RequireGroup(s.config.AdminGroup()))
+		admin.GET("/email-subscriptions", s.handler.GetAdminEmailSubscriptionsHandler)
 	}
 
 	r.NoRoute(gin.WrapH(s.webAppFileSystemHandler))
@@ -51,52 +81,78 @@ func (s *Router) GetRouter() *gin.Engine {
 	return r
 }
 
-func getEmailFromAuthorizationHeader(authHeader string, jwkURL string) (email string, err error) {
-	const BearerSchema = "Bearer "
-	if authHeader == "" {
-		return "", fmt.Errorf("no auth header")
+// RequestID is a middleware that assigns a correlation ID to each request, reusing the client's
+// X-Request-ID when it's valid. The ID and a logger tagged with it are carried by the request context.
+func (s *Router) RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestId := c.GetHeader(correlation.RequestIdHeader)
+		if !correlation.IsValidId(requestId) {
+			requestId = correlation.NewId()
+		}
+		c.Header(correlation.RequestIdHeader, requestId)
+		ctx := correlation.NewContext(c.Request.Context(), requestId)
+		ctx = logger.NewContext(ctx, s.logger.With("correlationId", requestId))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
 	}
+}
 
-	tokenString := strings.TrimSpace(strings.TrimPrefix(authHeader, BearerSchema))
-	if tokenString == "" {
-		return "", fmt.Errorf("no token string")
+// AccessLog is a middleware that logs every request once it's handled. It must run after RequestID
+// so that the log line carries the correlation ID.
+func (s *Router) AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		logger.FromContext(c.Request.Context(), s.logger).InfoWithContext(
+			"request handled",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"duration", time.Since(start).String(),
+		)
 	}
+}
 
-	jwkSet, err := jwk.Fetch(context.Background(), jwkURL)
-	if err != nil {
-		return "", fmt.Errorf("error while fetching JWK set => %v", err.Error())
+// RequestTimeout is a middleware that cancels the request context after the timeout so that a slow
+// downstream call fails the request instead of hanging it
+func (s *Router) RequestTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
 	}
+}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+// RequireAuthentication is a middleware that rejects requests without a valid Cognito token and
+// stores the verified claims on the context for the handlers
+func (s *Router) RequireAuthentication() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := s.authenticator.authenticate(c.Request.Context(), c.GetHeader("Authorization"))
+		if err != nil {
+			logger.FromContext(c.Request.Context(), s.logger).InfoWithContext("request authentication failed", "path", c.FullPath(), "error", err.Error())
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
 		}
-		keyID, ok := token.Header["kid"].(string)
-		if !ok {
-			return nil, fmt.Errorf("invalid key ID")
-		}
-		jwtKey, ok := jwkSet.LookupKeyID(keyID)
-		if !ok {
-			return nil, fmt.Errorf("no key found with ID: %s", keyID)
-		}
-		var tokenKey interface{}
-		if err := jwtKey.Raw(&tokenKey); err != nil {
-			return nil, fmt.Errorf("error while getting raw token key: %s", err)
-		}
-		return tokenKey, nil
-	})
-
-	if err != nil || !token.Valid {
-		return "", fmt.Errorf("parsed token is not valid => %v", err.Error())
+		c.Set(handler.AuthClaimsKey, claims)
+		c.Next()
 	}
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return "", fmt.Errorf("claims are not of type MapClaims")
-	}
-	// Verify and parse the 'email' claim
-	email, ok = claims["email"].(string)
-	if !ok {
-		return "", fmt.Errorf("email claim is not of type string")
+}
+
+// RequireGroup is a middleware that rejects authenticated users outside of the Cognito group. It must
+// run after RequireAuthentication.
+func (s *Router) RequireGroup(group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get(handler.AuthClaimsKey)
+		if claims, ok := value.(*handler.AuthClaims); ok {
+			for _, claimsGroup := range claims.Groups {
+				if claimsGroup == group {
+					c.Next()
+					return
+				}
+			}
+		}
+		logger.FromContext(c.Request.Context(), s.logger).InfoWithContext("request authorization failed", "path", c.FullPath(), "group", group)
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 	}
-	return email, nil
 }
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"score/app/correlation"
+	"score/app/logger"
+	"score/app/runners/server/handler"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := &Router{logger: logger.NewNop()}
+	engine := gin.New()
+	engine.Use(router.RequestID())
+	var contextCorrelationId string
+	engine.GET("/test", func(c *gin.Context) {
+		contextCorrelationId = correlation.FromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	t.Run("incoming request id is reused", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/test", nil)
+		request.Header.Set(correlation.RequestIdHeader, "request-1")
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, request)
+		require.Equal(t, "request-1", recorder.Header().Get(correlation.RequestIdHeader), "Response should echo the request ID")
+		require.Equal(t, "request-1", contextCorrelationId, "Request context should carry the request ID")
+	})
+
+	t.Run("request id is generated", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/test", nil)
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, request)
+		generatedId := recorder.Header().Get(correlation.RequestIdHeader)
+		require.NotEmpty(t, generatedId, "A request ID should be generated")
+		require.Equal(t, generatedId, contextCorrelationId, "Request context should carry the generated request ID")
+	})
+
+	t.Run("invalid request id is replaced", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/test", nil)
+		request.Header.Set(correlation.RequestIdHeader, "bad id\twith whitespace")
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, request)
+		require.NotEqual(t, "bad id\twith whitespace", recorder.Header().Get(correlation.RequestIdHeader), "Invalid request IDs should not be propagated")
+	})
+}
+
+func TestRequireGroup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := &Router{logger: logger.NewNop()}
+	newEngine := func(claims *handler.AuthClaims) *gin.Engine {
+		engine := gin.New()
+		engine.GET("/admin", func(c *gin.Context) {
+			if claims != nil {
+				c.Set(handler.AuthClaimsKey, claims)
+			}
+			c.Next()
+		}, router.RequireGroup("admin"), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		return engine
+	}
+
+	t.Run("members are allowed", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		newEngine(&handler.AuthClaims{Groups: []string{"users", "admin"}}).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin", nil))
+		require.Equal(t, http.StatusOK, recorder.Code, "Members of the group should be allowed")
+	})
+
+	t.Run("other users are forbidden", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		newEngine(&handler.AuthClaims{Groups: []string{"users"}}).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin", nil))
+		require.Equal(t, http.StatusForbidden, recorder.Code, "Users outside of the group should be forbidden")
+	})
+
+	t.Run("unauthenticated requests are forbidden", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		newEngine(nil).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin", nil))
+		require.Equal(t, http.StatusForbidden, recorder.Code, "Requests without claims should be forbidden")
+	})
+}
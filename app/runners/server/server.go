@@ -1,32 +1,150 @@
 package server
 
 import (
+	"context"
 	"embed"
 	"fmt"
 	"io/fs"
-	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"score/app/config"
+	"score/app/logger"
+	"score/app/runners/server/handler"
+	"score/app/services/aws/dynamodb"
+	"score/app/services/aws/ses"
+	"score/app/services/aws/sns"
+	"score/app/services/datastore"
+	"score/app/services/email"
+	"score/app/services/eventpub"
+	"score/app/services/memory"
+	"score/app/services/relational"
+	"score/app/services/user"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	listenAddress = ":3000"
+	// shutdownTimeout is how long in flight requests get to finish once the server is asked to stop
+	shutdownTimeout = 15 * time.Second
 )
 
+// Parameters are the config parameters server mode loads
+var Parameters = append([]config.ConfigParameterName{
+	config.PlatformEventsSnsTopicArnParameterName,
+	config.EmailSubscriptionsTableNameParameterName,
+	config.WebAppDomainNameParameterName,
+	config.MainTransactionalSendingAddressParameterName,
+	config.CognitoUserPoolIdParameterName,
+	config.CognitoAppClientIdParameterName,
+	config.ConfigRefreshIntervalParameterName,
+	config.KeyValueStoreFileParameterName,
+	config.AdminGroupParameterName,
+}, config.MySQLParameters...)
+
 var Run = func(webapp embed.FS) error {
-	fmt.Println("Running server...")
+	fmt.Println("Running score in server mode...")
+	// Gin runs in debug mode unless told otherwise, which local runs can still ask for with GIN_MODE
+	if os.Getenv(gin.EnvGinMode) == "" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	awsSession := session.Must(session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	}))
+	configSources, err := config.SourcesFromEnvironment(awsSession)
+	if err != nil {
+		return fmt.Errorf("error initializing app config sources: %v", err.Error())
+	}
+	configService := config.New(Parameters, configSources...)
+	err = configService.InitializeParameters(context.Background())
+	if err != nil {
+		return fmt.Errorf("error initializing app config: %v", err.Error())
+	}
 
-	fsys, err := fs.Sub(webapp, "dist")
+	// Set up the logger
+	loggerService, err := logger.New(false)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("error initializing logger: %v", err.Error())
 	}
 
-	http.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
-		// Handle API requests here
-		fmt.Fprintf(w, "Hello from the API!")
-	})
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
 
-	http.Handle("/", http.FileServer(http.FS(fsys)))
+	// Re-read the config in the background when a refresh interval is configured
+	if refreshInterval := configService.ConfigRefreshInterval(); refreshInterval > 0 {
+		go configService.RunRefresher(ctx, refreshInterval, loggerService)
+	}
 
-	log.Println("Listening on :3000...")
-	err = http.ListenAndServe(":3000", nil)
+	// Build the web app file system
+	webAppFileSystem, err := fs.Sub(webapp, "dist")
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("error loading web app file system: %v", err.Error())
 	}
-	return err
+
+	// Build application dependencies
+	sesService := ses.New(awsSession)
+	snsService := sns.New(awsSession, configService)
+	relationalDB, err := relational.New(context.Background(), configService)
+	if err != nil {
+		return fmt.Errorf("error initializing relational database: %v", err.Error())
+	}
+	defer relationalDB.Close()
+	configService.Subscribe(config.RelationalDatabaseDSNParameterName, relational.ReconnectOnDsnChange(relationalDB, loggerService))
+	var kvStore datastore.KeyValueStore = dynamodb.New(awsSession, configService)
+	if path := configService.KeyValueStoreFile(); path != "" {
+		kvStore, err = memory.NewFileKeyValueStore(path)
+		if err != nil {
+			return fmt.Errorf("error initializing key value store: %v", err.Error())
+		}
+	}
+	datastoreService := datastore.New(kvStore, relationalDB)
+	eventPublisherService := eventpub.New(snsService, configService)
+	userService := user.New(datastoreService, eventPublisherService)
+	emailService := email.New(sesService, datastoreService, loggerService, eventPublisherService)
+	routeHandler := handler.New(emailService, userService, loggerService)
+	router := New(ctx, routeHandler, configService, loggerService, newWebAppHandler(webAppFileSystem))
+
+	httpServer := &http.Server{
+		Addr:    listenAddress,
+		Handler: router.GetRouter(),
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		loggerService.InfoWithContext("server listening", "address", listenAddress)
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	loggerService.InfoWithContext("server shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("error shutting down server: %v", err.Error())
+	}
+	return nil
+}
+
+// newWebAppHandler serves files from the web app build and falls back to index.html for unknown
+// paths so that client side routes resolve to the single page app. Directories also get index.html
+// so that their contents aren't listed.
+func newWebAppHandler(webAppFileSystem fs.FS) http.Handler {
+	fileServer := http.FileServer(http.FS(webAppFileSystem))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		if path != "/" {
+			if info, err := fs.Stat(webAppFileSystem, strings.TrimSuffix(path[1:], "/")); err != nil || info.IsDir() {
+				r.URL.Path = "/"
+			}
+		}
+		fileServer.ServeHTTP(w, r)
+	})
 }
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebAppHandler(t *testing.T) {
+	webAppHandler := newWebAppHandler(fstest.MapFS{
+		"index.html":       {Data: []byte("index")},
+		"assets/app.js":    {Data: []byte("app")},
+		"assets/style.css": {Data: []byte("style")},
+	})
+	tests := []struct {
+		name         string
+		path         string
+		expectedBody string
+	}{
+		{name: "root", path: "/", expectedBody: "index"},
+		{name: "file", path: "/assets/app.js", expectedBody: "app"},
+		{name: "client side route", path: "/settings/profile", expectedBody: "index"},
+		{name: "directory", path: "/assets/", expectedBody: "index"},
+		{name: "directory without trailing slash", path: "/assets", expectedBody: "index"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			webAppHandler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, test.path, nil))
+			require.Equal(t, http.StatusOK, recorder.Code, "The web app should be served")
+			require.Equal(t, test.expectedBody, recorder.Body.String(), "The path should serve the expected file")
+		})
+	}
+}
@@ -1,20 +1,37 @@
 package handler
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"score/app/models"
+	"score/app/runners/worker/registry"
 )
 
+func init() {
+	registrations = append(registrations, func(s *EventHandler, r *registry.Registry) {
+		registry.Register(r, "account-confirmation-task", registry.DetailsDecoder[AccountConfirmationTaskEvent], s.AccountConfirmationTask)
+	})
+}
+
 type AccountConfirmationTaskEvent struct {
 	UserName     string `json:"userName"`
 	EmailAddress string `json:"emailAddress"`
 }
 
-func (s *EventHandler) AccountConfirmationTask(eventString string) error {
-	event := AccountConfirmationTaskEvent{}
-	err := json.Unmarshal([]byte(eventString), &event)
-	if err != nil {
-		return fmt.Errorf("error parsing event details: %s", err.Error())
+func (s *EventHandler) AccountConfirmationTask(ctx context.Context, event AccountConfirmationTaskEvent) error {
+	err := s.datastore.CreateUser(ctx, event.EmailAddress, event.UserName)
+	if !errors.Is(err, models.ErrUserAlreadyExists) {
+		return err
+	}
+	// A redelivered task finds the user it created the first time. Any other user holding the email
+	// or the Cognito user name is a conflict that must not be acknowledged.
+	user, getErr := s.datastore.GetUserByCognitoUserName(ctx, event.UserName)
+	if getErr != nil && !errors.Is(getErr, models.ErrUserNotFound) {
+		return fmt.Errorf("error getting user %v after a duplicate create: %w", event.UserName, getErr)
+	}
+	if user != nil && user.Email == event.EmailAddress {
+		return nil
 	}
-	return s.datastore.CreateUser(event.EmailAddress, event.UserName)
+	return err
 }
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"context"
+	"score/app/runners/worker/registry"
+)
+
+func init() {
+	registrations = append(registrations, func(s *EventHandler, r *registry.Registry) {
+		registry.Register(r, "account-deleted", registry.DetailsDecoder[AccountDeletedEvent], s.AccountDeleted)
+	})
+}
+
+type AccountDeletedEvent struct {
+	EmailAddress string `json:"emailAddress"`
+}
+
+// AccountDeleted acknowledges account deletions. The user and the email subscription are removed
+// synchronously by the API, so there is nothing left for the worker to clean up.
+func (s *EventHandler) AccountDeleted(ctx context.Context, event AccountDeletedEvent) error {
+	return nil
+}
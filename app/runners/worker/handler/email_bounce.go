@@ -1,31 +1,41 @@
 package handler
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"score/app/runners/worker/registry"
 	"time"
 )
 
-func (s *EventHandler) EmailBounce(eventString string) error {
-	event := EmailBounceEvent{}
-	err := json.Unmarshal([]byte(eventString), &event)
-	if err != nil {
-		return fmt.Errorf("error parsing event details: %s", err.Error())
-	}
+func init() {
+	registrations = append(registrations, func(s *EventHandler, r *registry.Registry) {
+		registry.Register(r, "Bounce", decodeEmailBounceEvent, s.EmailBounce)
+	})
+}
+
+func (s *EventHandler) EmailBounce(ctx context.Context, event EmailBounceEvent) error {
 	emailAddresses := []string{}
 	for _, recipient := range event.Bounce.BouncedRecipients {
 		emailAddresses = append(emailAddresses, recipient.EmailAddress)
 	}
 	return s.emailService.ProcessEmailBounce(
+		ctx,
 		emailAddresses,
 		event.Bounce.BounceType,
 		event.Bounce.BounceSubType,
-		eventString,
+		event.Message,
 		event.Bounce.Timestamp.Unix(),
 	)
 }
 
+// decodeEmailBounceEvent decodes the SES notification and keeps the original message to store as the bounce details
+func decodeEmailBounceEvent(event registry.Event) (EmailBounceEvent, error) {
+	payload, err := registry.MessageDecoder[EmailBounceEvent](event)
+	payload.Message = event.Message
+	return payload, err
+}
+
 type EmailBounceEvent struct {
+	Message   string      `json:"-"`
 	EventType string      `json:"eventType"`
 	Bounce    EmailBounce `json:"bounce"`
 	Mail      BounceMail  `json:"mail"`
@@ -1,25 +1,34 @@
 package handler
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"score/app/runners/worker/registry"
 	"time"
 )
 
-func (s *EventHandler) EmailComplaint(eventString string) error {
-	event := EmailComplaintEvent{}
-	err := json.Unmarshal([]byte(eventString), &event)
-	if err != nil {
-		return fmt.Errorf("error parsing event details: %s", err.Error())
-	}
+func init() {
+	registrations = append(registrations, func(s *EventHandler, r *registry.Registry) {
+		registry.Register(r, "Complaint", decodeEmailComplaintEvent, s.EmailComplaint)
+	})
+}
+
+func (s *EventHandler) EmailComplaint(ctx context.Context, event EmailComplaintEvent) error {
 	emailAddresses := []string{}
 	for _, recipient := range event.Complaint.ComplainedRecipients {
 		emailAddresses = append(emailAddresses, recipient.EmailAddress)
 	}
-	return s.emailService.ProcessEmailComplaint(emailAddresses, eventString, event.Complaint.Timestamp.Unix())
+	return s.emailService.ProcessEmailComplaint(ctx, emailAddresses, event.Message, event.Complaint.Timestamp.Unix())
+}
+
+// decodeEmailComplaintEvent decodes the SES notification and keeps the original message to store as the complaint details
+func decodeEmailComplaintEvent(event registry.Event) (EmailComplaintEvent, error) {
+	payload, err := registry.MessageDecoder[EmailComplaintEvent](event)
+	payload.Message = event.Message
+	return payload, err
 }
 
 type EmailComplaintEvent struct {
+	Message   string         `json:"-"`
 	EventType string         `json:"eventType"`
 	Complaint EmailComplaint `json:"complaint"`
 	Mail      ComplaintMail  `json:"mail"`
@@ -1,30 +1,35 @@
 package handler
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"score/app/runners/worker/registry"
 )
 
+func init() {
+	registrations = append(registrations, func(s *EventHandler, r *registry.Registry) {
+		registry.Register(r, "email-send-task", registry.DetailsDecoder[EmailSendTaskEvent], s.EmailSendTask)
+	})
+}
+
 type EmailSendTaskEvent struct {
 	TemplateName  string            `json:"templateName"`
 	SenderAddress string            `json:"senderAddress"`
 	SubjectLine   string            `json:"subjectLine"`
 	ToAddresses   []string          `json:"toAddresses"`
 	Parameters    map[string]string `json:"parameters"`
+	Headers       map[string]string `json:"headers"`
 }
 
-func (s *EventHandler) EmailSendTask(eventString string) error {
-	event := EmailSendTaskEvent{}
-	err := json.Unmarshal([]byte(eventString), &event)
-	if err != nil {
-		return fmt.Errorf("error parsing event details: %s", err.Error())
-	}
-	err = s.emailService.SendTemplatedEmail(
+func (s *EventHandler) EmailSendTask(ctx context.Context, event EmailSendTaskEvent) error {
+	err := s.emailService.SendTemplatedEmail(
+		ctx,
 		event.TemplateName,
 		event.Parameters,
 		event.SubjectLine,
 		event.SenderAddress,
 		event.ToAddresses,
+		event.Headers,
 	)
 	if err != nil {
 		return fmt.Errorf("error sending templated email: %s", err.Error())
@@ -1,5 +1,15 @@
 package handler
 
+import (
+	"context"
+	"score/app/models"
+	"score/app/runners/worker/registry"
+)
+
+// registrations holds the functions registering each event handler. Every handler file appends its
+// own registration in an init function, so adding an event only requires adding a file.
+var registrations []func(s *EventHandler, r *registry.Registry)
+
 type EventHandler struct {
 	emailService EmailService
 	datastore    Datastore
@@ -12,18 +22,28 @@ func New(emailService EmailService, datastore Datastore) *EventHandler {
 	}
 }
 
+// Register adds all event handlers to the registry
+func (s *EventHandler) Register(r *registry.Registry) {
+	for _, register := range registrations {
+		register(s, r)
+	}
+}
+
 type EmailService interface {
 	SendTemplatedEmail(
+		ctx context.Context,
 		templateName string,
 		templateParams map[string]string,
 		subjectLine string,
 		senderAddress string,
 		toAddresses []string,
+		headers map[string]string,
 	) error
-	ProcessEmailComplaint(complainedEmailAddresses []string, complaintDetails string, complaintUnixTime int64) error
-	ProcessEmailBounce(bouncedEmailAddresses []string, bounceType, bounceSubType, bounceDetails string, bounceUnixTime int64) error
+	ProcessEmailComplaint(ctx context.Context, complainedEmailAddresses []string, complaintDetails string, complaintUnixTime int64) error
+	ProcessEmailBounce(ctx context.Context, bouncedEmailAddresses []string, bounceType, bounceSubType, bounceDetails string, bounceUnixTime int64) error
 }
 
 type Datastore interface {
-	CreateUser(email, cognitoUserName string) error
+	CreateUser(ctx context.Context, email, cognitoUserName string) error
+	GetUserByCognitoUserName(ctx context.Context, cognitoUserName string) (*models.User, error)
 }
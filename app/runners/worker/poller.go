@@ -0,0 +1,214 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"score/app/config"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+const (
+	// pollWaitTimeSeconds is the SQS long polling duration (the maximum SQS allows)
+	pollWaitTimeSeconds = 20
+	// pollMaxMessages is the maximum number of messages SQS returns per receive
+	pollMaxMessages = 10
+	// visibilityTimeoutSeconds is how long a received message stays hidden from other consumers
+	visibilityTimeoutSeconds = 60
+	// visibilityExtensionInterval is how often the visibility of a message still being processed is extended
+	visibilityExtensionInterval = 30 * time.Second
+	// receiveErrorBackoff is how long to wait before polling again after a failed receive
+	receiveErrorBackoff = 5 * time.Second
+	// messageProcessingTimeout bounds how long a single message may be processed before it's failed
+	messageProcessingTimeout = 5 * time.Minute
+)
+
+// PollerParameters are the config parameters worker-poll mode loads
+var PollerParameters = append([]config.ConfigParameterName{
+	config.WorkerTasksQueueUrlParameterName,
+	config.SqsEndpointUrlParameterName,
+	config.WorkerPollConcurrencyParameterName,
+	config.ConfigRefreshIntervalParameterName,
+}, Parameters...)
+
+// RunPoller runs the worker as a long running process that polls the worker tasks queue,
+// as an alternative to running it inside AWS Lambda
+var RunPoller = func() error {
+	fmt.Println("Running score in worker polling mode...")
+	deps, err := initialize(PollerParameters)
+	if err != nil {
+		return err
+	}
+	defer deps.relationalDB.Close()
+	configService, loggerService := deps.config, deps.logger
+	if configService.WorkerTasksQueueUrl() == "" {
+		return fmt.Errorf("worker tasks queue url is not configured")
+	}
+
+	sqsConfig := aws.NewConfig()
+	if configService.SqsEndpointUrl() != "" {
+		sqsConfig = sqsConfig.WithEndpoint(configService.SqsEndpointUrl())
+	}
+	poller := newQueuePoller(
+		sqs.New(deps.awsSession, sqsConfig),
+		configService.WorkerTasksQueueUrl(),
+		eventRouter,
+		loggerService,
+		configService.WorkerPollConcurrency(),
+	)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	if refreshInterval := configService.ConfigRefreshInterval(); refreshInterval > 0 {
+		go configService.RunRefresher(ctx, refreshInterval, loggerService)
+	}
+	loggerService.InfoWithContext("polling worker tasks queue", "queueUrl", configService.WorkerTasksQueueUrl())
+	poller.run(ctx)
+	loggerService.InfoWithContext("polling worker stopped")
+	return nil
+}
+
+type queueClient interface {
+	ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error)
+	ChangeMessageVisibilityWithContext(ctx aws.Context, input *sqs.ChangeMessageVisibilityInput, opts ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error)
+	DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput, opts ...request.Option) (*sqs.DeleteMessageOutput, error)
+}
+
+// queuePoller long polls an SQS queue and processes messages with a bounded number of goroutines
+type queuePoller struct {
+	queue       queueClient
+	queueUrl    string
+	router      eventProcessor
+	logger      Logger
+	concurrency int
+}
+
+func newQueuePoller(queue queueClient, queueUrl string, router eventProcessor, logger Logger, concurrency int) *queuePoller {
+	return &queuePoller{
+		queue:       queue,
+		queueUrl:    queueUrl,
+		router:      router,
+		logger:      logger,
+		concurrency: concurrency,
+	}
+}
+
+// run polls until the context is cancelled, then waits for in flight messages to finish
+func (s *queuePoller) run(ctx context.Context) {
+	slots := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		// Only receive as many messages as there are free slots so that messages don't wait
+		// for a goroutine while their visibility timeout runs down
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		acquired := 1
+		for acquired < pollMaxMessages && tryAcquireSlot(slots) {
+			acquired++
+		}
+
+		output, err := s.queue.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(s.queueUrl),
+			MaxNumberOfMessages: aws.Int64(int64(acquired)),
+			WaitTimeSeconds:     aws.Int64(pollWaitTimeSeconds),
+			VisibilityTimeout:   aws.Int64(visibilityTimeoutSeconds),
+		})
+		if err != nil {
+			releaseSlots(slots, acquired)
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.ErrorWithContext("error receiving messages", "queueUrl", s.queueUrl, "error", err.Error())
+			select {
+			case <-time.After(receiveErrorBackoff):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		releaseSlots(slots, acquired-len(output.Messages))
+		for _, message := range output.Messages {
+			wg.Add(1)
+			go func(msg *sqs.Message) {
+				defer wg.Done()
+				defer releaseSlots(slots, 1)
+				s.handleMessage(msg)
+			}(message)
+		}
+	}
+}
+
+// handleMessage processes a message and deletes it on success. Failed messages are left on the
+// queue to become visible again once their visibility timeout expires. Messages are processed with
+// a context that isn't cancelled on shutdown so that in flight messages can finish, but that expires
+// after messageProcessingTimeout so that a hung dependency doesn't hold a slot forever.
+func (s *queuePoller) handleMessage(msg *sqs.Message) {
+	ctx := context.Background()
+	done := make(chan struct{})
+	defer close(done)
+	go s.extendVisibility(ctx, msg, done)
+
+	processingCtx, cancel := context.WithTimeout(ctx, messageProcessingTimeout)
+	defer cancel()
+	err := s.router.ProcessEvent(processingCtx, aws.StringValue(msg.Body))
+	if err != nil {
+		s.logger.ErrorWithContext("failed to process message", "messageId", aws.StringValue(msg.MessageId), "error", err.Error())
+		return
+	}
+	_, err = s.queue.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(s.queueUrl),
+		ReceiptHandle: msg.ReceiptHandle,
+	})
+	if err != nil {
+		s.logger.ErrorWithContext("error deleting message", "messageId", aws.StringValue(msg.MessageId), "error", err.Error())
+	}
+}
+
+// extendVisibility keeps a slow message hidden from other consumers until processing is done
+func (s *queuePoller) extendVisibility(ctx context.Context, msg *sqs.Message, done <-chan struct{}) {
+	ticker := time.NewTicker(visibilityExtensionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			_, err := s.queue.ChangeMessageVisibilityWithContext(ctx, &sqs.ChangeMessageVisibilityInput{
+				QueueUrl:          aws.String(s.queueUrl),
+				ReceiptHandle:     msg.ReceiptHandle,
+				VisibilityTimeout: aws.Int64(visibilityTimeoutSeconds),
+			})
+			if err != nil {
+				s.logger.ErrorWithContext("error extending message visibility", "messageId", aws.StringValue(msg.MessageId), "error", err.Error())
+			}
+		}
+	}
+}
+
+func tryAcquireSlot(slots chan struct{}) bool {
+	select {
+	case slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func releaseSlots(slots chan struct{}, count int) {
+	for i := 0; i < count; i++ {
+		<-slots
+	}
+}
@@ -0,0 +1,87 @@
+package worker
+
+import (
+	"context"
+	"score/app/logger"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQueue hands out its pending messages on the first receive and then long polls until cancelled
+type fakeQueue struct {
+	mutex   sync.Mutex
+	pending []*sqs.Message
+	deleted []string
+}
+
+func (s *fakeQueue) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	s.mutex.Lock()
+	count := int(aws.Int64Value(input.MaxNumberOfMessages))
+	if count > len(s.pending) {
+		count = len(s.pending)
+	}
+	messages := s.pending[:count]
+	s.pending = s.pending[count:]
+	s.mutex.Unlock()
+	if len(messages) > 0 {
+		return &sqs.ReceiveMessageOutput{Messages: messages}, nil
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (s *fakeQueue) ChangeMessageVisibilityWithContext(ctx aws.Context, input *sqs.ChangeMessageVisibilityInput, opts ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error) {
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+func (s *fakeQueue) DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput, opts ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.deleted = append(s.deleted, aws.StringValue(input.ReceiptHandle))
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func (s *fakeQueue) deletedHandles() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.deleted...)
+}
+
+func TestQueuePoller(t *testing.T) {
+	queue := &fakeQueue{}
+	for _, body := range []string{"body-1", "body-2", "body-3"} {
+		queue.pending = append(queue.pending, &sqs.Message{
+			MessageId:     aws.String("id-" + body),
+			ReceiptHandle: aws.String("handle-" + body),
+			Body:          aws.String(body),
+		})
+	}
+	router := &fakeEventRouter{failingBodies: map[string]bool{"body-2": true}}
+	poller := newQueuePoller(queue, "https://queue.local/tasks", router, logger.NewNop(), 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		poller.run(ctx)
+		close(stopped)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(queue.deletedHandles()) == 2
+	}, time.Second, 10*time.Millisecond, "Successfully processed messages should be deleted")
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Poller should stop once the context is cancelled")
+	}
+
+	require.ElementsMatch(t, []string{"handle-body-1", "handle-body-3"}, queue.deletedHandles(), "Failed messages should not be deleted")
+	require.ElementsMatch(t, []string{"body-1", "body-2", "body-3"}, router.processed, "All messages should be processed")
+}
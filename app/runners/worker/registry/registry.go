@@ -0,0 +1,99 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownEvent is returned for events that no handler is registered for
+var ErrUnknownEvent = errors.New("unknown event")
+
+// Event is a message received by the worker
+type Event struct {
+	EventId       string `json:"eventId"`
+	EventName     string `json:"eventType"`
+	CorrelationId string `json:"correlationId"`
+	EventDetails  string `json:"eventDetails"`
+	// Message is the raw message the event was parsed from
+	Message string `json:"-"`
+}
+
+// Decoder builds the typed payload a handler expects from an event
+type Decoder[T any] func(event Event) (T, error)
+
+// DetailsDecoder decodes the JSON event details of platform events published by score
+func DetailsDecoder[T any](event Event) (T, error) {
+	var payload T
+	err := json.Unmarshal([]byte(event.EventDetails), &payload)
+	return payload, err
+}
+
+// MessageDecoder decodes the whole message, for events such as SES notifications that
+// aren't wrapped in a platform event
+func MessageDecoder[T any](event Event) (T, error) {
+	var payload T
+	err := json.Unmarshal([]byte(event.Message), &payload)
+	return payload, err
+}
+
+// FallbackHandler handles events that no handler is registered for
+type FallbackHandler func(ctx context.Context, event Event) error
+
+type Logger interface {
+	InfoWithContext(message string, keysAndValues ...interface{})
+	ErrorWithContext(message string, keysAndValues ...interface{})
+	DebugWithContext(message string, keysAndValues ...interface{})
+}
+
+// LogAndAcknowledge logs unknown events and reports them as processed so they are removed from the queue
+func LogAndAcknowledge(logger Logger) FallbackHandler {
+	return func(ctx context.Context, event Event) error {
+		logger.InfoWithContext("acknowledging unknown event", "eventName", event.EventName, "correlationId", event.CorrelationId)
+		return nil
+	}
+}
+
+// DeadLetter fails unknown events so that they are retried and eventually moved to the
+// queue's dead-letter queue by its redrive policy
+func DeadLetter(ctx context.Context, event Event) error {
+	return fmt.Errorf("%w: %s", ErrUnknownEvent, event.EventName)
+}
+
+// Registry maps event names to the handlers that process them
+type Registry struct {
+	handlers map[string]func(ctx context.Context, event Event) error
+	fallback FallbackHandler
+}
+
+func New(fallback FallbackHandler) *Registry {
+	return &Registry{
+		handlers: map[string]func(ctx context.Context, event Event) error{},
+		fallback: fallback,
+	}
+}
+
+// Register adds the handler for an event name. Registering two handlers for the same event is a
+// programming error and panics.
+func Register[T any](r *Registry, eventName string, decode Decoder[T], handle func(ctx context.Context, payload T) error) {
+	if _, exists := r.handlers[eventName]; exists {
+		panic(fmt.Sprintf("duplicate handler registered for event: %s", eventName))
+	}
+	r.handlers[eventName] = func(ctx context.Context, event Event) error {
+		payload, err := decode(event)
+		if err != nil {
+			return fmt.Errorf("error parsing event details: %s", err.Error())
+		}
+		return handle(ctx, payload)
+	}
+}
+
+// Dispatch passes the event to its registered handler, or to the fallback handler if there is none
+func (r *Registry) Dispatch(ctx context.Context, event Event) error {
+	handler, exists := r.handlers[event.EventName]
+	if !exists {
+		return r.fallback(ctx, event)
+	}
+	return handler(ctx, event)
+}
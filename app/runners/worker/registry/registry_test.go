@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testPayload struct {
+	Value string `json:"value"`
+}
+
+func TestDispatch(t *testing.T) {
+	t.Run("registered event", func(t *testing.T) {
+		registry := New(DeadLetter)
+		var received testPayload
+		Register(registry, "test-event", DetailsDecoder[testPayload], func(ctx context.Context, payload testPayload) error {
+			received = payload
+			return nil
+		})
+		err := registry.Dispatch(context.Background(), Event{EventName: "test-event", EventDetails: `{"value":"hello"}`})
+		require.NoError(t, err, "Dispatching a registered event should not return an error")
+		require.Equal(t, "hello", received.Value, "Handler should receive the decoded payload")
+	})
+
+	t.Run("message decoder", func(t *testing.T) {
+		registry := New(DeadLetter)
+		var received testPayload
+		Register(registry, "test-event", MessageDecoder[testPayload], func(ctx context.Context, payload testPayload) error {
+			received = payload
+			return nil
+		})
+		err := registry.Dispatch(context.Background(), Event{EventName: "test-event", Message: `{"eventType":"test-event","value":"raw"}`})
+		require.NoError(t, err, "Dispatching a registered event should not return an error")
+		require.Equal(t, "raw", received.Value, "Handler should receive the payload decoded from the whole message")
+	})
+
+	t.Run("invalid payload", func(t *testing.T) {
+		registry := New(DeadLetter)
+		Register(registry, "test-event", DetailsDecoder[testPayload], func(ctx context.Context, payload testPayload) error {
+			t.Fatal("Handler should not be called with an invalid payload")
+			return nil
+		})
+		err := registry.Dispatch(context.Background(), Event{EventName: "test-event", EventDetails: "not json"})
+		require.Error(t, err, "Dispatching an invalid payload should return an error")
+	})
+
+	t.Run("dead letter fallback", func(t *testing.T) {
+		registry := New(DeadLetter)
+		err := registry.Dispatch(context.Background(), Event{EventName: "unknown-event"})
+		require.True(t, errors.Is(err, ErrUnknownEvent), "Unknown events should fail with ErrUnknownEvent")
+	})
+
+	t.Run("custom fallback", func(t *testing.T) {
+		var fallbackEvent Event
+		registry := New(func(ctx context.Context, event Event) error {
+			fallbackEvent = event
+			return nil
+		})
+		err := registry.Dispatch(context.Background(), Event{EventName: "unknown-event"})
+		require.NoError(t, err, "Fallback result should be returned")
+		require.Equal(t, "unknown-event", fallbackEvent.EventName, "Unknown events should be passed to the fallback")
+	})
+
+	t.Run("duplicate registration", func(t *testing.T) {
+		registry := New(DeadLetter)
+		handle := func(ctx context.Context, payload testPayload) error { return nil }
+		Register(registry, "test-event", DetailsDecoder[testPayload], handle)
+		require.Panics(t, func() {
+			Register(registry, "test-event", DetailsDecoder[testPayload], handle)
+		}, "Registering the same event twice should panic")
+	})
+}
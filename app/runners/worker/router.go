@@ -1,66 +1,105 @@
 package worker
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"score/app/correlation"
+	"score/app/logger"
+	"score/app/models"
+	"score/app/runners/worker/registry"
+	"time"
 )
 
+// deduplicationStoreTimeout bounds the calls recording the outcome of an event
+const deduplicationStoreTimeout = 5 * time.Second
+
 type EventRouter struct {
-	eventHandler EventHandler
-	logger       Logger
+	registry           *registry.Registry
+	deduplicationStore DeduplicationStore
+	logger             Logger
 }
 
-func NewRouter(eventHandler EventHandler, logger Logger) *EventRouter {
+func NewRouter(registry *registry.Registry, deduplicationStore DeduplicationStore, logger Logger) *EventRouter {
 	return &EventRouter{
-		eventHandler: eventHandler,
-		logger:       logger,
+		registry:           registry,
+		deduplicationStore: deduplicationStore,
+		logger:             logger,
 	}
 }
 
-type EventHandler interface {
-	EmailSendTask(eventDetails string) error
-	AccountConfirmationTask(eventDetails string) error
-	EmailBounce(eventDetails string) error
-	EmailComplaint(eventDetails string) error
+// DeduplicationStore records the IDs of claimed and processed events. ClaimEvent fails with
+// models.ErrEventAlreadyProcessed for processed events, and with models.ErrEventInProgress while
+// another delivery of the event holds a claim on it.
+type DeduplicationStore interface {
+	ClaimEvent(ctx context.Context, eventId string) error
+	ReleaseEvent(ctx context.Context, eventId string) error
+	AddProcessedEvent(ctx context.Context, eventId string) error
 }
 
 type Logger interface {
 	InfoWithContext(message string, keysAndValues ...interface{})
 	ErrorWithContext(message string, keysAndValues ...interface{})
 	DebugWithContext(message string, keysAndValues ...interface{})
+	With(keysAndValues ...interface{}) logger.Interface
 }
 
-type Event struct {
-	EventName     string `json:"eventType"`
-	CorrelationId string `json:"correlationId"`
-	EventDetails  string `json:"eventDetails"`
-}
-
-func (s *EventRouter) ProcessEvent(eventString string) error {
-	event := Event{}
+func (s *EventRouter) ProcessEvent(ctx context.Context, eventString string) error {
+	event := registry.Event{}
 	err := json.Unmarshal([]byte(eventString), &event)
 	if err != nil {
 		return fmt.Errorf("error parsing event: %s", err.Error())
 	}
-	if event.EventName == "Bounce" || event.EventName == "Complaint" {
-		event.EventDetails = eventString
-	}
-	switch event.EventName {
-	case "email-send-task":
-		err = s.eventHandler.EmailSendTask(event.EventDetails)
-	case "account-confirmation-task":
-		err = s.eventHandler.AccountConfirmationTask(event.EventDetails)
-	case "Bounce":
-		err = s.eventHandler.EmailBounce(event.EventDetails)
-	case "Complaint":
-		err = s.eventHandler.EmailComplaint(event.EventDetails)
-	default:
-		err = fmt.Errorf("unsupported event name")
+	event.Message = eventString
+	eventId := deduplicationId(event)
+	// Handlers log through a logger tagged with the event's correlation ID so that their log lines
+	// can be tied back to the request that published the event
+	eventLogger := s.logger.With("eventName", event.EventName, "eventId", eventId, "correlationId", event.CorrelationId)
+	ctx = correlation.NewContext(ctx, event.CorrelationId)
+	ctx = logger.NewContext(ctx, eventLogger)
+
+	err = s.deduplicationStore.ClaimEvent(ctx, eventId)
+	switch {
+	case errors.Is(err, models.ErrEventAlreadyProcessed):
+		eventLogger.InfoWithContext("skipping duplicate event")
+		return nil
+	case errors.Is(err, models.ErrEventInProgress):
+		// The delivery holding the claim may still fail, so this one is retried rather than acknowledged
+		eventLogger.InfoWithContext("event is being processed by another delivery")
+		return err
+	case err != nil:
+		eventLogger.ErrorWithContext("error claiming event", "errorMessage", err.Error())
+		return err
 	}
+	err = s.registry.Dispatch(ctx, event)
+	// The deduplication store is updated even when ctx ran out while the event was handled
+	storeCtx, cancel := context.WithTimeout(context.Background(), deduplicationStoreTimeout)
+	defer cancel()
 	if err != nil {
-		s.logger.ErrorWithContext("error processing event", "eventName", event.EventName, "errorMessage", err.Error(), "correlationId", event.CorrelationId)
+		eventLogger.ErrorWithContext("error processing event", "errorMessage", err.Error())
+		// Without the release the redelivery would be retried until the claim expires
+		if releaseErr := s.deduplicationStore.ReleaseEvent(storeCtx, eventId); releaseErr != nil {
+			eventLogger.ErrorWithContext("error releasing event claim", "errorMessage", releaseErr.Error())
+		}
 		return err
 	}
-	s.logger.InfoWithContext("successfully processed event", "eventName", event.EventName, "eventDetails", event.EventDetails, "correlationId", event.CorrelationId)
+	// The event was handled, so failing here would only cause it to be handled again
+	if err := s.deduplicationStore.AddProcessedEvent(storeCtx, eventId); err != nil {
+		eventLogger.ErrorWithContext("error recording processed event", "errorMessage", err.Error())
+	}
+	eventLogger.InfoWithContext("successfully processed event", "eventDetails", event.EventDetails)
 	return nil
 }
+
+// deduplicationId returns the ID of the event. Events that don't carry an ID, such as SES
+// notifications, are identified by a hash of their message since redeliveries are identical.
+func deduplicationId(event registry.Event) string {
+	if event.EventId != "" {
+		return event.EventId
+	}
+	hash := sha256.Sum256([]byte(event.Message))
+	return "sha256:" + hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,139 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"score/app/correlation"
+	"score/app/logger"
+	"score/app/models"
+	"score/app/runners/worker/registry"
+	"score/app/services/memory"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testPayload struct {
+	Value string `json:"value"`
+}
+
+func newTestRouter(handle func(ctx context.Context, payload testPayload) error) *EventRouter {
+	eventRegistry := registry.New(registry.DeadLetter)
+	registry.Register(eventRegistry, "test-event", registry.DetailsDecoder[testPayload], handle)
+	return NewRouter(eventRegistry, memory.NewDeduplicationStore(), logger.NewNop())
+}
+
+func TestProcessEventDeduplication(t *testing.T) {
+	t.Run("duplicate event id is handled once", func(t *testing.T) {
+		calls := 0
+		router := newTestRouter(func(ctx context.Context, payload testPayload) error {
+			calls++
+			return nil
+		})
+		event := `{"eventId":"event-1","eventType":"test-event","eventDetails":"{\"value\":\"a\"}"}`
+		require.NoError(t, router.ProcessEvent(context.Background(), event), "First delivery should succeed")
+		require.NoError(t, router.ProcessEvent(context.Background(), event), "Duplicate delivery should be acknowledged")
+		require.Equal(t, 1, calls, "Handler should only run once")
+	})
+
+	t.Run("distinct event ids are handled", func(t *testing.T) {
+		calls := 0
+		router := newTestRouter(func(ctx context.Context, payload testPayload) error {
+			calls++
+			return nil
+		})
+		require.NoError(t, router.ProcessEvent(context.Background(), `{"eventId":"event-1","eventType":"test-event","eventDetails":"{}"}`), "First event should succeed")
+		require.NoError(t, router.ProcessEvent(context.Background(), `{"eventId":"event-2","eventType":"test-event","eventDetails":"{}"}`), "Second event should succeed")
+		require.Equal(t, 2, calls, "Handler should run for each event")
+	})
+
+	t.Run("events without id are deduplicated by message", func(t *testing.T) {
+		calls := 0
+		router := newTestRouter(func(ctx context.Context, payload testPayload) error {
+			calls++
+			return nil
+		})
+		event := `{"eventType":"test-event","eventDetails":"{}"}`
+		require.NoError(t, router.ProcessEvent(context.Background(), event), "First delivery should succeed")
+		require.NoError(t, router.ProcessEvent(context.Background(), event), "Duplicate delivery should be acknowledged")
+		require.Equal(t, 1, calls, "Handler should only run once for identical messages")
+	})
+
+	t.Run("failed events are retried", func(t *testing.T) {
+		calls := 0
+		router := newTestRouter(func(ctx context.Context, payload testPayload) error {
+			calls++
+			if calls == 1 {
+				return errors.New("temporary failure")
+			}
+			return nil
+		})
+		event := `{"eventId":"event-1","eventType":"test-event","eventDetails":"{}"}`
+		require.Error(t, router.ProcessEvent(context.Background(), event), "First delivery should fail")
+		require.NoError(t, router.ProcessEvent(context.Background(), event), "Redelivery should be handled")
+		require.Equal(t, 2, calls, "Failed events should not be recorded as processed")
+	})
+
+	t.Run("failed events are released after the context is cancelled", func(t *testing.T) {
+		calls := 0
+		router := newTestRouter(func(ctx context.Context, payload testPayload) error {
+			calls++
+			if calls == 1 {
+				return context.Canceled
+			}
+			return nil
+		})
+		event := `{"eventId":"event-1","eventType":"test-event","eventDetails":"{}"}`
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.Error(t, router.ProcessEvent(ctx, event), "First delivery should fail")
+		require.NoError(t, router.ProcessEvent(context.Background(), event), "Redelivery should be handled")
+		require.Equal(t, 2, calls, "Cancelled deliveries should release their claim")
+	})
+}
+
+func TestProcessEventConcurrentDeliveries(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	router := newTestRouter(func(ctx context.Context, payload testPayload) error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return nil
+	})
+	event := `{"eventId":"event-1","eventType":"test-event","eventDetails":"{}"}`
+	const deliveries = 10
+	errs := make(chan error, deliveries)
+	var wg sync.WaitGroup
+	for i := 0; i < deliveries; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- router.ProcessEvent(context.Background(), event)
+		}()
+	}
+	// The handler is held until every duplicate delivery has returned, so they all arrive while the
+	// first delivery is being processed
+	for i := 0; i < deliveries-1; i++ {
+		require.ErrorIs(t, <-errs, models.ErrEventInProgress, "Duplicate deliveries should be retried")
+	}
+	close(release)
+	wg.Wait()
+	require.NoError(t, <-errs, "The claimed delivery should succeed")
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls), "Handler should only run once for concurrent deliveries")
+}
+
+func TestProcessEventCorrelation(t *testing.T) {
+	var correlationId string
+	var hasLogger bool
+	router := newTestRouter(func(ctx context.Context, payload testPayload) error {
+		correlationId = correlation.FromContext(ctx)
+		hasLogger = logger.FromContext(ctx, nil) != nil
+		return nil
+	})
+	err := router.ProcessEvent(context.Background(), `{"eventId":"event-1","eventType":"test-event","correlationId":"request-1","eventDetails":"{}"}`)
+	require.NoError(t, err, "Processing the event should succeed")
+	require.Equal(t, "request-1", correlationId, "Handler context should carry the event's correlation ID")
+	require.True(t, hasLogger, "Handler context should carry a request scoped logger")
+}
@@ -7,105 +7,164 @@ import (
 	"score/app/config"
 	"score/app/logger"
 	"score/app/runners/worker/handler"
+	"score/app/runners/worker/registry"
 	"score/app/services/aws/dynamodb"
 	"score/app/services/aws/ses"
 	"score/app/services/aws/sns"
 	"score/app/services/datastore"
 	"score/app/services/email"
 	"score/app/services/eventpub"
-	"score/app/services/mysql"
+	"score/app/services/memory"
+	"score/app/services/relational"
 	"score/app/services/user"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/sqs"
 )
 
-var eventRouter *EventRouter
+// lambdaDeadlineMargin is how long before the Lambda deadline message processing is cancelled, which
+// leaves time to report the failed messages before the invocation is killed
+const lambdaDeadlineMargin = 2 * time.Second
+
+// Parameters are the config parameters worker mode loads
+var Parameters = append([]config.ConfigParameterName{
+	config.PlatformEventsSnsTopicArnParameterName,
+	config.EmailSubscriptionsTableNameParameterName,
+	config.ProcessedEventsTableNameParameterName,
+	config.WebAppDomainNameParameterName,
+	config.MainTransactionalSendingAddressParameterName,
+	config.WorkerUnknownEventPolicyParameterName,
+	config.KeyValueStoreFileParameterName,
+}, config.MySQLParameters...)
+
+// dependencies are the services built by initialize that the runners need to hold on to
+type dependencies struct {
+	awsSession   *session.Session
+	config       *config.Config
+	logger       *logger.Logger
+	relationalDB relational.Database
+}
+
+type eventProcessor interface {
+	ProcessEvent(ctx context.Context, eventString string) error
+}
+
+var eventRouter eventProcessor
 
 var Run = func() error {
 	fmt.Println("Running score in worker mode...")
+	// The pool isn't closed since Lambda freezes and eventually kills the process without notice
+	if _, err := initialize(Parameters); err != nil {
+		return err
+	}
+
+	lambda.Start(lambdaEventHandler)
+
+	return nil
+}
+
+// initialize loads the app config and builds the event router shared by the Lambda and polling workers
+func initialize(parameterNames []config.ConfigParameterName) (*dependencies, error) {
 	awsSession := session.Must(session.NewSessionWithOptions(session.Options{
 		SharedConfigState: session.SharedConfigEnable,
 	}))
-	configService := config.New(awsSession)
-	err := configService.InitializeParameters()
+	configSources, err := config.SourcesFromEnvironment(awsSession)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing app config sources: %v", err.Error())
+	}
+	configService := config.New(parameterNames, configSources...)
+	err = configService.InitializeParameters(context.Background())
 	if err != nil {
-		return fmt.Errorf("error initializing app config: %v", err.Error())
+		return nil, fmt.Errorf("error initializing app config: %v", err.Error())
 	}
 
 	// Set up the logger
 	loggerService, err := logger.New(false)
 	if err != nil {
-		return fmt.Errorf("error initializing logger: %v", err.Error())
+		return nil, fmt.Errorf("error initializing logger: %v", err.Error())
 	}
 
 	// Build application dependencies
 	sesService := ses.New(awsSession)
 	snsService := sns.New(awsSession, configService)
-	mysqlService := mysql.New(configService)
+	relationalDB, err := relational.New(context.Background(), configService)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing relational database: %v", err.Error())
+	}
+	configService.Subscribe(config.RelationalDatabaseDSNParameterName, relational.ReconnectOnDsnChange(relationalDB, loggerService))
 	dynamoDbService := dynamodb.New(awsSession, configService)
-	datastoreService := datastore.New(dynamoDbService, mysqlService)
+	var kvStore datastore.KeyValueStore = dynamoDbService
+	var deduplicationStore DeduplicationStore = dynamoDbService
+	// A local key value store file means that there's no DynamoDB to deduplicate events with either
+	if path := configService.KeyValueStoreFile(); path != "" {
+		kvStore, err = memory.NewFileKeyValueStore(path)
+		if err != nil {
+			return nil, fmt.Errorf("error initializing key value store: %v", err.Error())
+		}
+		deduplicationStore = memory.NewDeduplicationStore()
+	}
+	datastoreService := datastore.New(kvStore, relationalDB)
 	eventPublisherService := eventpub.New(snsService, configService)
-	userService := user.New(datastoreService)
+	userService := user.New(datastoreService, eventPublisherService)
 	emailService := email.New(sesService, datastoreService, loggerService, eventPublisherService)
 	platformEventHandler := handler.New(emailService, userService)
-	eventRouter = NewRouter(platformEventHandler, loggerService)
-
-	lambda.Start(lambdaEventHandler)
+	eventRegistry := registry.New(unknownEventFallback(configService.WorkerUnknownEventPolicy(), loggerService))
+	platformEventHandler.Register(eventRegistry)
+	eventRouter = NewRouter(eventRegistry, deduplicationStore, loggerService)
+
+	return &dependencies{
+		awsSession:   awsSession,
+		config:       configService,
+		logger:       loggerService,
+		relationalDB: relationalDB,
+	}, nil
+}
 
-	return nil
+// unknownEventFallback picks how events without a registered handler are handled. Unless they are
+// explicitly acknowledged, they are failed so that they end up on the dead-letter queue.
+func unknownEventFallback(policy string, logger Logger) registry.FallbackHandler {
+	if policy == config.AcknowledgeUnknownEvents {
+		return registry.LogAndAcknowledge(logger)
+	}
+	return registry.DeadLetter
 }
 
-func lambdaEventHandler(ctx context.Context, sqsEvent events.SQSEvent) error {
+// lambdaEventHandler processes a batch of SQS messages concurrently and reports the IDs of the messages
+// that failed so that only those are retried. The SQS event source mapping must be configured with the
+// ReportBatchItemFailures function response type for the response to be honoured. Messages still being
+// processed shortly before the invocation deadline are cancelled and reported as failed.
+func lambdaEventHandler(ctx context.Context, sqsEvent events.SQSEvent) (events.SQSEventResponse, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline.Add(-lambdaDeadlineMargin))
+		defer cancel()
+	}
 	var wg sync.WaitGroup
-	errorChan := make(chan error, len(sqsEvent.Records))
-
-	svc := sqs.New(session.Must(session.NewSession()))
+	var mutex sync.Mutex
+	response := events.SQSEventResponse{
+		BatchItemFailures: []events.SQSBatchItemFailure{},
+	}
 
 	for _, sqsMessage := range sqsEvent.Records {
 		wg.Add(1)
 		go func(msg events.SQSMessage) {
 			defer wg.Done()
-			err := eventRouter.ProcessEvent(msg.Body)
+			err := eventRouter.ProcessEvent(ctx, msg.Body)
 			if err != nil {
-				errorChan <- fmt.Errorf("failed to process message %s: %v", msg.MessageId, err)
-				// Change visibility timeout for failed messages
-				_, changeVisibilityErr := svc.ChangeMessageVisibility(&sqs.ChangeMessageVisibilityInput{
-					QueueUrl:          &msg.EventSourceARN,
-					ReceiptHandle:     &msg.ReceiptHandle,
-					VisibilityTimeout: aws.Int64(0), // Since the message failed, we want it to be immediately visible again
+				log.Printf("Failed to process message with ID %s: %v", msg.MessageId, err)
+				mutex.Lock()
+				response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{
+					ItemIdentifier: msg.MessageId,
 				})
-
-				if changeVisibilityErr != nil {
-					log.Printf("Error changing visibility of message with ID %s: %v", msg.MessageId, changeVisibilityErr)
-				}
-				return
-			}
-			svc.DeleteMessage(&sqs.DeleteMessageInput{
-				QueueUrl:      &msg.EventSourceARN,
-				ReceiptHandle: &msg.ReceiptHandle,
-			})
-			if err != nil {
-				log.Printf("Error deleting message with ID %s: %v", msg.MessageId, err)
+				mutex.Unlock()
 			}
 		}(sqsMessage)
 	}
 
 	wg.Wait()
-	close(errorChan)
-
-	errorMessages := []string{}
 
-	for err := range errorChan {
-		errorMessages = append(errorMessages, err.Error())
-	}
-	if len(errorMessages) > 0 {
-		return fmt.Errorf("failed to process %d messages: %v", len(errorMessages), errorMessages)
-	}
-
-	return nil
+	return response, nil
 }
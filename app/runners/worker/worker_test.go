@@ -0,0 +1,96 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEventRouter struct {
+	mutex         sync.Mutex
+	failingBodies map[string]bool
+	hangingBodies map[string]bool
+	processed     []string
+}
+
+func (s *fakeEventRouter) ProcessEvent(ctx context.Context, eventString string) error {
+	s.mutex.Lock()
+	s.processed = append(s.processed, eventString)
+	s.mutex.Unlock()
+	if s.hangingBodies[eventString] {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if s.failingBodies[eventString] {
+		return errors.New("processing failed")
+	}
+	return nil
+}
+
+func withFakeEventRouter(t *testing.T, router *fakeEventRouter) {
+	originalRouter := eventRouter
+	eventRouter = router
+	t.Cleanup(func() { eventRouter = originalRouter })
+}
+
+func TestLambdaEventHandler(t *testing.T) {
+	t.Run("all messages succeed", func(t *testing.T) {
+		router := &fakeEventRouter{}
+		withFakeEventRouter(t, router)
+		response, err := lambdaEventHandler(context.Background(), events.SQSEvent{
+			Records: []events.SQSMessage{
+				{MessageId: "message-1", Body: "body-1"},
+				{MessageId: "message-2", Body: "body-2"},
+			},
+		})
+		require.NoError(t, err, "Handler should not return an error")
+		require.Empty(t, response.BatchItemFailures, "No messages should be reported as failed")
+		require.ElementsMatch(t, []string{"body-1", "body-2"}, router.processed, "All messages should be processed")
+	})
+
+	t.Run("only failed messages are reported", func(t *testing.T) {
+		router := &fakeEventRouter{failingBodies: map[string]bool{"body-2": true, "body-3": true}}
+		withFakeEventRouter(t, router)
+		response, err := lambdaEventHandler(context.Background(), events.SQSEvent{
+			Records: []events.SQSMessage{
+				{MessageId: "message-1", Body: "body-1"},
+				{MessageId: "message-2", Body: "body-2"},
+				{MessageId: "message-3", Body: "body-3"},
+			},
+		})
+		require.NoError(t, err, "Handler should not fail the whole batch")
+		require.ElementsMatch(t, []events.SQSBatchItemFailure{
+			{ItemIdentifier: "message-2"},
+			{ItemIdentifier: "message-3"},
+		}, response.BatchItemFailures, "Only the failed messages should be reported")
+	})
+
+	t.Run("messages still processing near the deadline are reported", func(t *testing.T) {
+		withFakeEventRouter(t, &fakeEventRouter{hangingBodies: map[string]bool{"body-2": true}})
+		ctx, cancel := context.WithTimeout(context.Background(), lambdaDeadlineMargin+100*time.Millisecond)
+		defer cancel()
+		response, err := lambdaEventHandler(ctx, events.SQSEvent{
+			Records: []events.SQSMessage{
+				{MessageId: "message-1", Body: "body-1"},
+				{MessageId: "message-2", Body: "body-2"},
+			},
+		})
+		require.NoError(t, err, "Handler should not fail the whole batch")
+		require.NoError(t, ctx.Err(), "Handler should return before the invocation deadline")
+		require.Equal(t, []events.SQSBatchItemFailure{
+			{ItemIdentifier: "message-2"},
+		}, response.BatchItemFailures, "The message that ran past the deadline should be reported")
+	})
+
+	t.Run("empty batch", func(t *testing.T) {
+		withFakeEventRouter(t, &fakeEventRouter{})
+		response, err := lambdaEventHandler(context.Background(), events.SQSEvent{})
+		require.NoError(t, err, "Handler should not return an error")
+		require.Empty(t, response.BatchItemFailures, "No messages should be reported as failed")
+	})
+}
@@ -1,11 +1,17 @@
 package dynamodb
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"score/app/models"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
@@ -27,9 +33,37 @@ func New(awsSession *session.Session, config iConfig) *DynamoDB {
 
 type iConfig interface {
 	EmailSubscriptionsTableName() string
+	ProcessedEventsTableName() string
 }
 
+// Condition expressions on the email subscriptions table's key, so that creating a subscription
+// doesn't overwrite another one and updates don't create items for unknown emails
+const (
+	emailSubscriptionNotExistsCondition = "attribute_not_exists(email)"
+	emailSubscriptionExistsCondition    = "attribute_exists(email)"
+)
+
+// maxScansPerPage bounds how many Scan requests a listing page may take, since a selective filter
+// can leave most scanned items out. A page cut short by the bound is returned with a cursor.
+const maxScansPerPage = 10
+
+// processedEventRetention is how long processed event IDs are kept for deduplication. Expired items
+// are removed by the table's TTL on the expires_at attribute.
+const processedEventRetention = 7 * 24 * time.Hour
+
+// eventClaimTimeout is how long a claim keeps other deliveries of the event from processing it. It
+// matches the queue's visibility timeout, so a message redelivered after its worker died finds the
+// claim expired.
+const eventClaimTimeout = time.Minute
+
+// Values of the event_status attribute of the processed events table
+const (
+	eventStatusClaimed   = "claimed"
+	eventStatusProcessed = "processed"
+)
+
 func (s *DynamoDB) itemExists(
+	ctx context.Context,
 	tableName string,
 	key map[string]*dynamodb.AttributeValue,
 ) (bool, error) {
@@ -37,7 +71,7 @@ func (s *DynamoDB) itemExists(
 		TableName: aws.String(tableName),
 		Key:       key,
 	}
-	result, err := s.svc.GetItem(getInput)
+	result, err := s.svc.GetItemWithContext(ctx, getInput)
 	if err != nil {
 		return false, err
 	}
@@ -47,24 +81,37 @@ func (s *DynamoDB) itemExists(
 	return false, nil
 }
 
+// putItem writes the item. A non empty conditionExpression must hold for the write to happen,
+// otherwise it fails with conditionFailedErr.
 func (s *DynamoDB) putItem(
+	ctx context.Context,
 	tableName string,
 	item map[string]*dynamodb.AttributeValue,
+	conditionExpression string,
+	conditionFailedErr error,
 ) error {
 	putInput := &dynamodb.PutItemInput{
 		TableName: aws.String(tableName),
 		Item:      item,
 	}
-	_, err := s.svc.PutItem(putInput)
-	return err
+	if conditionExpression != "" {
+		putInput.ConditionExpression = aws.String(conditionExpression)
+	}
+	_, err := s.svc.PutItemWithContext(ctx, putInput)
+	return mapConditionalCheckFailed(err, conditionFailedErr)
 }
 
+// updateItem updates the item. A non empty conditionExpression must hold for the update to happen,
+// otherwise it fails with conditionFailedErr.
 func (s *DynamoDB) updateItem(
+	ctx context.Context,
 	tableName string,
 	key map[string]*dynamodb.AttributeValue,
 	expressionAttributeValues map[string]*dynamodb.AttributeValue,
 	updateExpression string,
 	returnValues string,
+	conditionExpression string,
+	conditionFailedErr error,
 ) error {
 	updateInput := &dynamodb.UpdateItemInput{
 		TableName:                 aws.String(tableName),
@@ -73,23 +120,39 @@ func (s *DynamoDB) updateItem(
 		UpdateExpression:          aws.String(updateExpression),
 		ReturnValues:              aws.String(returnValues),
 	}
-	_, err := s.svc.UpdateItem(updateInput)
+	if conditionExpression != "" {
+		updateInput.ConditionExpression = aws.String(conditionExpression)
+	}
+	_, err := s.svc.UpdateItemWithContext(ctx, updateInput)
+	return mapConditionalCheckFailed(err, conditionFailedErr)
+}
+
+// mapConditionalCheckFailed replaces the error of a write whose condition expression didn't hold
+func mapConditionalCheckFailed(err error, conditionFailedErr error) error {
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return conditionFailedErr
+	}
 	return err
 }
 
-func (s *DynamoDB) EmailSubscriptionItemExists(email string) (bool, error) {
-	tableName := s.config.EmailSubscriptionsTableName()
-	key := map[string]*dynamodb.AttributeValue{
-		"email": {
-			S: aws.String(email),
-		},
+func (s *DynamoDB) deleteItem(
+	ctx context.Context,
+	tableName string,
+	key map[string]*dynamodb.AttributeValue,
+) error {
+	deleteInput := &dynamodb.DeleteItemInput{
+		TableName: aws.String(tableName),
+		Key:       key,
 	}
-	return s.itemExists(tableName, key)
+	_, err := s.svc.DeleteItemWithContext(ctx, deleteInput)
+	return err
 }
 
-func (s *DynamoDB) VerifyEmailSubscription(email string) error {
+func (s *DynamoDB) VerifyEmailSubscription(ctx context.Context, email string) error {
 	tableName := s.config.EmailSubscriptionsTableName()
 	return s.updateItem(
+		ctx,
 		tableName,
 		map[string]*dynamodb.AttributeValue{
 			"email": {
@@ -101,14 +164,79 @@ func (s *DynamoDB) VerifyEmailSubscription(email string) error {
 				BOOL: aws.Bool(true),
 			},
 		},
-		"set email_verified = :value",
+		"set email_verified = :value remove subscription_token, subscription_token_issued, subscription_token_expiry",
 		"NONE",
+		emailSubscriptionExistsCondition,
+		fmt.Errorf("%w: %v", models.ErrEmailSubscriptionNotFound, email),
 	)
 }
 
+// RotateSubscriptionToken replaces the subscription token only while the stored token is still the
+// one issued at previousTokenIssuedUnix, so that of two concurrent rotations only one succeeds. The
+// other fails with models.ErrEmailSubscriptionChanged.
+func (s *DynamoDB) RotateSubscriptionToken(
+	ctx context.Context,
+	email string,
+	subscriptionToken string,
+	previousTokenIssuedUnix int64,
+	tokenIssuedUnix int64,
+	tokenExpiryUnix int64,
+) error {
+	tableName := s.config.EmailSubscriptionsTableName()
+	key := map[string]*dynamodb.AttributeValue{
+		"email": {
+			S: aws.String(email),
+		},
+	}
+	// Verified subscriptions have no subscription_token_issued attribute, which reads as zero
+	conditionExpression := emailSubscriptionExistsCondition + " AND subscription_token_issued = :previousTokenIssued"
+	if previousTokenIssuedUnix == 0 {
+		conditionExpression = emailSubscriptionExistsCondition + " AND (attribute_not_exists(subscription_token_issued) OR subscription_token_issued = :previousTokenIssued)"
+	}
+	err := s.updateItem(
+		ctx,
+		tableName,
+		key,
+		map[string]*dynamodb.AttributeValue{
+			":token": {
+				S: aws.String(subscriptionToken),
+			},
+			":previousTokenIssued": {
+				N: aws.String(fmt.Sprintf("%d", previousTokenIssuedUnix)),
+			},
+			":tokenIssued": {
+				N: aws.String(fmt.Sprintf("%d", tokenIssuedUnix)),
+			},
+			":tokenExpiry": {
+				N: aws.String(fmt.Sprintf("%d", tokenExpiryUnix)),
+			},
+		},
+		"set subscription_token = :token, subscription_token_issued = :tokenIssued, subscription_token_expiry = :tokenExpiry",
+		"NONE",
+		conditionExpression,
+		fmt.Errorf("%w: %v", models.ErrEmailSubscriptionChanged, email),
+	)
+	if !errors.Is(err, models.ErrEmailSubscriptionChanged) {
+		return err
+	}
+	// The condition also fails for missing subscriptions, which are reported as such
+	exists, existsErr := s.itemExists(ctx, tableName, key)
+	if existsErr != nil {
+		return existsErr
+	}
+	if !exists {
+		return fmt.Errorf("%w: %v", models.ErrEmailSubscriptionNotFound, email)
+	}
+	return err
+}
+
 func (s *DynamoDB) CreateEmailSubscriptionItem(
+	ctx context.Context,
 	email string,
 	subscriptionToken string,
+	unsubscribeToken string,
+	tokenIssuedUnix int64,
+	tokenExpiryUnix int64,
 	isVerified bool,
 ) error {
 	timestamp := time.Now().Unix()
@@ -123,16 +251,107 @@ func (s *DynamoDB) CreateEmailSubscriptionItem(
 		"subscription_token": {
 			S: aws.String(subscriptionToken),
 		},
+		"subscription_token_issued": {
+			N: aws.String(fmt.Sprintf("%d", tokenIssuedUnix)),
+		},
+		"subscription_token_expiry": {
+			N: aws.String(fmt.Sprintf("%d", tokenExpiryUnix)),
+		},
+		"unsubscribe_token": {
+			S: aws.String(unsubscribeToken),
+		},
 		"email_verified": {
 			BOOL: aws.Bool(isVerified),
 		},
 	}
-	return s.putItem(tableName, item)
+	return s.putItem(
+		ctx,
+		tableName,
+		item,
+		emailSubscriptionNotExistsCondition,
+		fmt.Errorf("%w: %v", models.ErrEmailSubscriptionExists, email),
+	)
+}
+
+// SetUnsubscribeToken stores the unsubscribe token of a subscription created before subscriptions
+// had one. It fails with models.ErrEmailSubscriptionChanged when the subscription already has one, so
+// that concurrent sends don't hand out different tokens.
+func (s *DynamoDB) SetUnsubscribeToken(ctx context.Context, email string, unsubscribeToken string) error {
+	tableName := s.config.EmailSubscriptionsTableName()
+	key := map[string]*dynamodb.AttributeValue{
+		"email": {
+			S: aws.String(email),
+		},
+	}
+	err := s.updateItem(
+		ctx,
+		tableName,
+		key,
+		map[string]*dynamodb.AttributeValue{
+			":unsubscribeToken": {
+				S: aws.String(unsubscribeToken),
+			},
+			":empty": {
+				S: aws.String(""),
+			},
+		},
+		"set unsubscribe_token = :unsubscribeToken",
+		"NONE",
+		emailSubscriptionExistsCondition+" AND (attribute_not_exists(unsubscribe_token) OR unsubscribe_token = :empty)",
+		fmt.Errorf("%w: %v", models.ErrEmailSubscriptionChanged, email),
+	)
+	if !errors.Is(err, models.ErrEmailSubscriptionChanged) {
+		return err
+	}
+	exists, existsErr := s.itemExists(ctx, tableName, key)
+	if existsErr != nil {
+		return existsErr
+	}
+	if !exists {
+		return fmt.Errorf("%w: %v", models.ErrEmailSubscriptionNotFound, email)
+	}
+	return err
+}
+
+func (s *DynamoDB) UnsubscribeEmailSubscription(ctx context.Context, email string, unsubscribeDateUnix int64) error {
+	tableName := s.config.EmailSubscriptionsTableName()
+	return s.updateItem(
+		ctx,
+		tableName,
+		map[string]*dynamodb.AttributeValue{
+			"email": {
+				S: aws.String(email),
+			},
+		},
+		map[string]*dynamodb.AttributeValue{
+			":unsubscribed": {
+				BOOL: aws.Bool(true),
+			},
+			":unsubscribeDate": {
+				N: aws.String(fmt.Sprintf("%d", unsubscribeDateUnix)),
+			},
+		},
+		"set unsubscribed = :unsubscribed, unsubscribe_date = :unsubscribeDate",
+		"NONE",
+		emailSubscriptionExistsCondition,
+		fmt.Errorf("%w: %v", models.ErrEmailSubscriptionNotFound, email),
+	)
+}
+
+func (s *DynamoDB) DeleteEmailSubscriptionItem(ctx context.Context, email string) error {
+	tableName := s.config.EmailSubscriptionsTableName()
+	key := map[string]*dynamodb.AttributeValue{
+		"email": {
+			S: aws.String(email),
+		},
+	}
+	return s.deleteItem(ctx, tableName, key)
 }
 
-func (s *DynamoDB) AddComplaintToEmailSubscription(email, complaintDetails string, complaintDateUnix int64) error {
+func (s *DynamoDB) AddComplaintToEmailSubscription(ctx context.Context, email, complaintDetails string, complaintDateUnix int64) error {
 	tableName := s.config.EmailSubscriptionsTableName()
 	return s.updateItem(
+		ctx,
 		tableName,
 		map[string]*dynamodb.AttributeValue{
 			"email": {
@@ -152,10 +371,13 @@ func (s *DynamoDB) AddComplaintToEmailSubscription(email, complaintDetails strin
 		},
 		"set has_complaint = :hasComplaint, complaint_date = :complaintDate, complaint_details = :complaintDetails",
 		"NONE",
+		emailSubscriptionExistsCondition,
+		fmt.Errorf("%w: %v", models.ErrEmailSubscriptionNotFound, email),
 	)
 }
 
 func (s *DynamoDB) AddBounceToEmailSubscription(
+	ctx context.Context,
 	email,
 	bounceType string,
 	bounceDetails string,
@@ -163,6 +385,7 @@ func (s *DynamoDB) AddBounceToEmailSubscription(
 ) error {
 	tableName := s.config.EmailSubscriptionsTableName()
 	return s.updateItem(
+		ctx,
 		tableName,
 		map[string]*dynamodb.AttributeValue{
 			"email": {
@@ -185,10 +408,12 @@ func (s *DynamoDB) AddBounceToEmailSubscription(
 		},
 		"set has_bounce = :hasBounce, bounce_date = :bounceDate, bounce_type = :bounceType, bounce_details = :bounceDetails",
 		"NONE",
+		emailSubscriptionExistsCondition,
+		fmt.Errorf("%w: %v", models.ErrEmailSubscriptionNotFound, email),
 	)
 }
 
-func (s *DynamoDB) GetEmailSubscription(email string) (*models.EmailSubscription, error) {
+func (s *DynamoDB) GetEmailSubscription(ctx context.Context, email string) (*models.EmailSubscription, error) {
 	tableName := s.config.EmailSubscriptionsTableName()
 	getInput := &dynamodb.GetItemInput{
 		TableName: aws.String(tableName),
@@ -198,7 +423,7 @@ func (s *DynamoDB) GetEmailSubscription(email string) (*models.EmailSubscription
 			},
 		},
 	}
-	result, err := s.svc.GetItem(getInput)
+	result, err := s.svc.GetItemWithContext(ctx, getInput)
 	if err != nil {
 		return nil, err
 	}
@@ -212,3 +437,209 @@ func (s *DynamoDB) GetEmailSubscription(email string) (*models.EmailSubscription
 	}
 	return emailSubscription, nil
 }
+
+// ListEmailSubscriptions scans the email subscriptions table. The cursor encodes the
+// LastEvaluatedKey of the scan that ended the previous page.
+func (s *DynamoDB) ListEmailSubscriptions(ctx context.Context, filter models.EmailSubscriptionFilter, cursor string, limit int) (*models.EmailSubscriptionPage, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("%w: %d", models.ErrInvalidPageLimit, limit)
+	}
+	startKey, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	scanInput := &dynamodb.ScanInput{
+		TableName:         aws.String(s.config.EmailSubscriptionsTableName()),
+		ExclusiveStartKey: startKey,
+	}
+	if filterExpression, names, values := emailSubscriptionFilterExpression(filter); filterExpression != "" {
+		scanInput.FilterExpression = aws.String(filterExpression)
+		scanInput.ExpressionAttributeNames = names
+		scanInput.ExpressionAttributeValues = values
+	}
+
+	page := &models.EmailSubscriptionPage{Subscriptions: []models.EmailSubscription{}}
+	for scans := 0; scans < maxScansPerPage; scans++ {
+		// Limit caps the items scanned rather than the items matching the filter, so a page never
+		// exceeds the limit and the last evaluated key is a valid place to continue from
+		scanInput.Limit = aws.Int64(int64(limit - len(page.Subscriptions)))
+		result, err := s.svc.ScanWithContext(ctx, scanInput)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions := []models.EmailSubscription{}
+		if err := dynamodbattribute.UnmarshalListOfMaps(result.Items, &subscriptions); err != nil {
+			return nil, err
+		}
+		page.Subscriptions = append(page.Subscriptions, subscriptions...)
+		if len(result.LastEvaluatedKey) == 0 {
+			return page, nil
+		}
+		scanInput.ExclusiveStartKey = result.LastEvaluatedKey
+		if len(page.Subscriptions) == limit {
+			break
+		}
+	}
+	page.NextCursor, err = encodeCursor(scanInput.ExclusiveStartKey)
+	return page, err
+}
+
+// emailSubscriptionFilterExpression builds the scan filter. Boolean attributes that were never set
+// are missing from the item, which counts as false.
+func emailSubscriptionFilterExpression(filter models.EmailSubscriptionFilter) (string, map[string]*string, map[string]*dynamodb.AttributeValue) {
+	conditions := []string{}
+	names := map[string]*string{}
+	values := map[string]*dynamodb.AttributeValue{}
+	addCondition := func(attribute string, value *bool) {
+		if value == nil {
+			return
+		}
+		name := "#" + attribute
+		names[name] = aws.String(attribute)
+		if *value {
+			values[":true"] = &dynamodb.AttributeValue{BOOL: aws.Bool(true)}
+			conditions = append(conditions, fmt.Sprintf("%s = :true", name))
+		} else {
+			values[":false"] = &dynamodb.AttributeValue{BOOL: aws.Bool(false)}
+			conditions = append(conditions, fmt.Sprintf("(attribute_not_exists(%s) OR %s = :false)", name, name))
+		}
+	}
+	addCondition("email_verified", filter.Verified)
+	addCondition("has_bounce", filter.Bounced)
+	addCondition("has_complaint", filter.Complained)
+	addCondition("unsubscribed", filter.Unsubscribed)
+	return strings.Join(conditions, " AND "), names, values
+}
+
+func encodeCursor(key map[string]*dynamodb.AttributeValue) (string, error) {
+	plainKey := map[string]interface{}{}
+	if err := dynamodbattribute.UnmarshalMap(key, &plainKey); err != nil {
+		return "", err
+	}
+	encodedKey, err := json.Marshal(plainKey)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(encodedKey), nil
+}
+
+func decodeCursor(cursor string) (map[string]*dynamodb.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	encodedKey, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidCursor, cursor)
+	}
+	plainKey := map[string]interface{}{}
+	if err := json.Unmarshal(encodedKey, &plainKey); err != nil || len(plainKey) == 0 {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidCursor, cursor)
+	}
+	key, err := dynamodbattribute.MarshalMap(plainKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidCursor, cursor)
+	}
+	return key, nil
+}
+
+// ClaimEvent records that the event is being processed. It fails with models.ErrEventAlreadyProcessed
+// when the event was processed, and with models.ErrEventInProgress while another delivery holds an
+// unexpired claim on it.
+func (s *DynamoDB) ClaimEvent(ctx context.Context, eventId string) error {
+	tableName := s.config.ProcessedEventsTableName()
+	key := map[string]*dynamodb.AttributeValue{
+		"event_id": {
+			S: aws.String(eventId),
+		},
+	}
+	now := time.Now()
+	putInput := &dynamodb.PutItemInput{
+		TableName: aws.String(tableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			"event_id": key["event_id"],
+			"event_status": {
+				S: aws.String(eventStatusClaimed),
+			},
+			"expires_at": {
+				N: aws.String(fmt.Sprintf("%d", now.Add(eventClaimTimeout).Unix())),
+			},
+		},
+		// TTL deletion can lag behind expiry, so expired items can be claimed again
+		ConditionExpression: aws.String("attribute_not_exists(event_id) OR expires_at <= :now"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {
+				N: aws.String(fmt.Sprintf("%d", now.Unix())),
+			},
+		},
+	}
+	_, err := s.svc.PutItemWithContext(ctx, putInput)
+	err = mapConditionalCheckFailed(err, fmt.Errorf("%w: %v", models.ErrEventInProgress, eventId))
+	if !errors.Is(err, models.ErrEventInProgress) {
+		return err
+	}
+	result, getErr := s.svc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(tableName),
+		Key:            key,
+		ConsistentRead: aws.Bool(true),
+	})
+	if getErr != nil {
+		return getErr
+	}
+	// Items written before claims existed have no status and were all processed
+	if status, ok := result.Item["event_status"]; result.Item != nil && (!ok || aws.StringValue(status.S) == eventStatusProcessed) {
+		return fmt.Errorf("%w: %v", models.ErrEventAlreadyProcessed, eventId)
+	}
+	return err
+}
+
+// ReleaseEvent removes the claim of an event that failed so that its redelivery is processed. Events
+// that were marked as processed in the meantime are left alone.
+func (s *DynamoDB) ReleaseEvent(ctx context.Context, eventId string) error {
+	_, err := s.svc.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.config.ProcessedEventsTableName()),
+		Key: map[string]*dynamodb.AttributeValue{
+			"event_id": {
+				S: aws.String(eventId),
+			},
+		},
+		ConditionExpression: aws.String("event_status = :claimed"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":claimed": {
+				S: aws.String(eventStatusClaimed),
+			},
+		},
+	})
+	return mapConditionalCheckFailed(err, nil)
+}
+
+// AddProcessedEvent turns the claim of a processed event into a record kept for processedEventRetention
+func (s *DynamoDB) AddProcessedEvent(ctx context.Context, eventId string) error {
+	tableName := s.config.ProcessedEventsTableName()
+	expiresAt := time.Now().Add(processedEventRetention).Unix()
+	item := map[string]*dynamodb.AttributeValue{
+		"event_id": {
+			S: aws.String(eventId),
+		},
+		"event_status": {
+			S: aws.String(eventStatusProcessed),
+		},
+		"expires_at": {
+			N: aws.String(fmt.Sprintf("%d", expiresAt)),
+		},
+	}
+	return s.putItem(ctx, tableName, item, "", nil)
+}
+
+// CheckTable verifies that the table exists and is active
+func (s *DynamoDB) CheckTable(ctx context.Context, tableName string) error {
+	output, err := s.svc.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return fmt.Errorf("error describing table %s => %v", tableName, err.Error())
+	}
+	if status := aws.StringValue(output.Table.TableStatus); status != dynamodb.TableStatusActive {
+		return fmt.Errorf("table %s is %s", tableName, status)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"score/app/services/datastore"
+	"score/app/services/datastore/kvtest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/require"
+)
+
+// localEndpointEnvVar points the tests at a local DynamoDB stand-in, e.g. http://localhost:8000
+const localEndpointEnvVar = "SCORE_TEST_DYNAMODB_ENDPOINT"
+
+type testConfig struct {
+	emailSubscriptionsTableName string
+}
+
+func (s testConfig) EmailSubscriptionsTableName() string {
+	return s.emailSubscriptionsTableName
+}
+
+func (s testConfig) ProcessedEventsTableName() string {
+	return ""
+}
+
+func TestKeyValueStore(t *testing.T) {
+	endpoint := os.Getenv(localEndpointEnvVar)
+	if endpoint == "" {
+		t.Skipf("%s is not set", localEndpointEnvVar)
+	}
+	ctx := context.Background()
+	awsSession := session.Must(session.NewSession(aws.NewConfig().
+		WithEndpoint(endpoint).
+		WithRegion("us-east-1").
+		WithCredentials(credentials.NewStaticCredentials("local", "local", ""))))
+
+	tableName := fmt.Sprintf("score-kvtest-%d", time.Now().UnixNano())
+	svc := dynamodb.New(awsSession)
+	_, err := svc.CreateTableWithContext(ctx, &dynamodb.CreateTableInput{
+		TableName:   aws.String(tableName),
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("email"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("email"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+		},
+	})
+	require.NoError(t, err, "Creating the test table should succeed")
+	defer svc.DeleteTableWithContext(ctx, &dynamodb.DeleteTableInput{TableName: aws.String(tableName)})
+	require.NoError(t, svc.WaitUntilTableExistsWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)}), "The test table should become active")
+
+	store := New(awsSession, testConfig{emailSubscriptionsTableName: tableName})
+	kvtest.Run(t, func(t *testing.T) datastore.KeyValueStore {
+		return store
+	})
+}
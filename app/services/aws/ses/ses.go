@@ -1,6 +1,14 @@
 package ses
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/mail"
+	"sort"
+	"strings"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ses"
@@ -18,12 +26,19 @@ func New(awsSession *session.Session) *SES {
 	}
 }
 
+// SendEmail sends a plain text email. When custom headers are provided the email is sent as a raw
+// message since the simple SES API doesn't support setting headers.
 func (s *SES) SendEmail(
+	ctx context.Context,
 	senderAddress string,
 	toAddresses []string,
 	subjectLine string,
 	emailBody string,
+	headers map[string]string,
 ) error {
+	if len(headers) > 0 {
+		return s.sendRawEmail(ctx, senderAddress, toAddresses, subjectLine, emailBody, headers)
+	}
 	email := &ses.SendEmailInput{
 		Source: aws.String(senderAddress),
 		Destination: &ses.Destination{
@@ -34,6 +49,95 @@ func (s *SES) SendEmail(
 			Body:    &ses.Body{Text: &ses.Content{Data: aws.String(emailBody)}},
 		},
 	}
-	_, err := s.svc.SendEmail(email)
+	_, err := s.svc.SendEmailWithContext(ctx, email)
 	return err
 }
+
+func (s *SES) sendRawEmail(
+	ctx context.Context,
+	senderAddress string,
+	toAddresses []string,
+	subjectLine string,
+	emailBody string,
+	headers map[string]string,
+) error {
+	message, err := buildRawMessage(senderAddress, toAddresses, subjectLine, emailBody, headers)
+	if err != nil {
+		return err
+	}
+	email := &ses.SendRawEmailInput{
+		Source:       aws.String(senderAddress),
+		Destinations: aws.StringSlice(toAddresses),
+		RawMessage:   &ses.RawMessage{Data: message},
+	}
+	_, err = s.svc.SendRawEmailWithContext(ctx, email)
+	return err
+}
+
+func buildRawMessage(
+	senderAddress string,
+	toAddresses []string,
+	subjectLine string,
+	emailBody string,
+	headers map[string]string,
+) ([]byte, error) {
+	message := &bytes.Buffer{}
+	writeHeader := func(name, value string) error {
+		if strings.ContainsAny(name+value, "\r\n") {
+			return fmt.Errorf("invalid email header: %s", name)
+		}
+		fmt.Fprintf(message, "%s: %s\r\n", name, value)
+		return nil
+	}
+	if err := writeHeader("From", senderAddress); err != nil {
+		return nil, err
+	}
+	if err := writeHeader("To", strings.Join(toAddresses, ", ")); err != nil {
+		return nil, err
+	}
+	if err := writeHeader("Subject", mime.QEncoding.Encode("utf-8", subjectLine)); err != nil {
+		return nil, err
+	}
+	// Sort the custom headers so the raw message is deterministic
+	headerNames := make([]string, 0, len(headers))
+	for name := range headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	for _, name := range headerNames {
+		if err := writeHeader(name, headers[name]); err != nil {
+			return nil, err
+		}
+	}
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	message.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(emailBody)
+	return message.Bytes(), nil
+}
+
+// CheckSendingIdentity verifies that the sender address, or its domain, is a verified SES identity
+func (s *SES) CheckSendingIdentity(ctx context.Context, senderAddress string) error {
+	address, err := mail.ParseAddress(senderAddress)
+	if err != nil {
+		return fmt.Errorf("invalid sender address => %v", err.Error())
+	}
+	identities := []string{address.Address}
+	if at := strings.LastIndex(address.Address, "@"); at != -1 {
+		identities = append(identities, address.Address[at+1:])
+	}
+	output, err := s.svc.GetIdentityVerificationAttributesWithContext(ctx, &ses.GetIdentityVerificationAttributesInput{
+		Identities: aws.StringSlice(identities),
+	})
+	if err != nil {
+		return fmt.Errorf("error getting identity verification attributes => %v", err.Error())
+	}
+	for _, identity := range identities {
+		if attributes, ok := output.VerificationAttributes[identity]; ok &&
+			aws.StringValue(attributes.VerificationStatus) == ses.VerificationStatusSuccess {
+			return nil
+		}
+	}
+	return fmt.Errorf("neither %s is a verified SES identity", strings.Join(identities, " nor "))
+}
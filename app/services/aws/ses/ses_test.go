@@ -0,0 +1,28 @@
+package ses
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildRawMessage(t *testing.T) {
+	headers := map[string]string{
+		"List-Unsubscribe":      "<https://example.com/v1/email-subscriptions/unsubscribe/one-click?token=abc>",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+	message, err := buildRawMessage("from@example.com", []string{"a@example.com", "b@example.com"}, "Subject", "body", headers)
+	require.NoError(t, err, "Building a message should succeed")
+
+	head, body, found := strings.Cut(string(message), "\r\n\r\n")
+	require.True(t, found, "The headers should be separated from the body by a blank line")
+	require.Equal(t, "body", body, "The body should follow the headers")
+	lines := strings.Split(head, "\r\n")
+	require.Contains(t, lines, "To: a@example.com, b@example.com", "The recipients should be listed")
+	require.Contains(t, lines, "List-Unsubscribe: <https://example.com/v1/email-subscriptions/unsubscribe/one-click?token=abc>", "The List-Unsubscribe header should be set")
+	require.Contains(t, lines, "List-Unsubscribe-Post: List-Unsubscribe=One-Click", "The List-Unsubscribe-Post header should be set")
+
+	_, err = buildRawMessage("from@example.com", []string{"a@example.com"}, "Subject", "body", map[string]string{"List-Unsubscribe": "<x>\r\nBcc: c@example.com"})
+	require.Error(t, err, "Header values with line breaks should be rejected")
+}
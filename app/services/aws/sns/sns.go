@@ -1,6 +1,8 @@
 package sns
 
 import (
+	"context"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sns"
 	"github.com/google/uuid"
@@ -24,9 +26,9 @@ type Config interface {
 	PlatformEventsTopicArn() string
 }
 
-func (s *SNS) publishMessageToTopic(message string, topicArn string) error {
+func (s *SNS) publishMessageToTopic(ctx context.Context, message string, topicArn string) error {
 	uniqueMessageId := uuid.New().String()
-	_, err := s.svc.Publish(&sns.PublishInput{
+	_, err := s.svc.PublishWithContext(ctx, &sns.PublishInput{
 		Message:                &message,
 		TopicArn:               &topicArn,
 		MessageGroupId:         &uniqueMessageId,
@@ -35,6 +37,14 @@ func (s *SNS) publishMessageToTopic(message string, topicArn string) error {
 	return err
 }
 
-func (s *SNS) PublishPlatformEventMessage(serializedPlatformEvent string) error {
-	return s.publishMessageToTopic(serializedPlatformEvent, s.config.PlatformEventsTopicArn())
+func (s *SNS) PublishPlatformEventMessage(ctx context.Context, serializedPlatformEvent string) error {
+	return s.publishMessageToTopic(ctx, serializedPlatformEvent, s.config.PlatformEventsTopicArn())
+}
+
+// CheckPlatformEventsTopic verifies that the platform events topic exists and is accessible
+func (s *SNS) CheckPlatformEventsTopic(ctx context.Context) error {
+	_, err := s.svc.GetTopicAttributesWithContext(ctx, &sns.GetTopicAttributesInput{
+		TopicArn: aws.String(s.config.PlatformEventsTopicArn()),
+	})
+	return err
 }
@@ -1,6 +1,7 @@
 package datastore
 
 import (
+	"context"
 	"score/app/models"
 )
 
@@ -17,46 +18,115 @@ func New(kvStore KeyValueStore, relationalDB RelationalDB) *Datastore {
 }
 
 type KeyValueStore interface {
-	EmailSubscriptionItemExists(email string) (bool, error)
-	AddComplaintToEmailSubscription(email, complaintDetails string, complaintDateUnix int64) error
-	AddBounceToEmailSubscription(email, bounceType, bounceDetails string, bounceDateUnix int64) error
-	GetEmailSubscription(email string) (*models.EmailSubscription, error)
-	CreateEmailSubscriptionItem(email string, subscriptionToken string, isVerified bool) error
-	VerifyEmailSubscription(email string) error
+	AddComplaintToEmailSubscription(ctx context.Context, email, complaintDetails string, complaintDateUnix int64) error
+	AddBounceToEmailSubscription(ctx context.Context, email, bounceType, bounceDetails string, bounceDateUnix int64) error
+	GetEmailSubscription(ctx context.Context, email string) (*models.EmailSubscription, error)
+	CreateEmailSubscriptionItem(ctx context.Context, email string, subscriptionToken, unsubscribeToken string, tokenIssuedUnix, tokenExpiryUnix int64, isVerified bool) error
+	VerifyEmailSubscription(ctx context.Context, email string) error
+	RotateSubscriptionToken(ctx context.Context, email string, subscriptionToken string, previousTokenIssuedUnix, tokenIssuedUnix, tokenExpiryUnix int64) error
+	SetUnsubscribeToken(ctx context.Context, email string, unsubscribeToken string) error
+	UnsubscribeEmailSubscription(ctx context.Context, email string, unsubscribeDateUnix int64) error
+	DeleteEmailSubscriptionItem(ctx context.Context, email string) error
+	ListEmailSubscriptions(ctx context.Context, filter models.EmailSubscriptionFilter, cursor string, limit int) (*models.EmailSubscriptionPage, error)
 }
 
 type RelationalDB interface {
-	CreateUser(email, cognitoUserName string) error
-}
-
-func (s *Datastore) EmailSubscriptionExists(email string) (bool, error) {
-	return s.kvStore.EmailSubscriptionItemExists(email)
+	CreateUser(ctx context.Context, email, cognitoUserName string) error
+	GetUserById(ctx context.Context, id string) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByCognitoUserName(ctx context.Context, cognitoUserName string) (*models.User, error)
+	UpdateUserEmail(ctx context.Context, id, email string) error
+	SoftDeleteUser(ctx context.Context, id string) error
+	DeleteUser(ctx context.Context, id string) error
+	DeleteUserByEmail(ctx context.Context, email string) error
 }
 
 func (s *Datastore) CreateEmailSubscription(
+	ctx context.Context,
 	email string,
 	subscriptionToken string,
+	unsubscribeToken string,
+	tokenIssuedUnix int64,
+	tokenExpiryUnix int64,
 	isVerified bool,
 ) error {
-	return s.kvStore.CreateEmailSubscriptionItem(email, subscriptionToken, isVerified)
+	return s.kvStore.CreateEmailSubscriptionItem(ctx, email, subscriptionToken, unsubscribeToken, tokenIssuedUnix, tokenExpiryUnix, isVerified)
+}
+
+func (s *Datastore) VerifyEmailSubscription(ctx context.Context, email string) error {
+	return s.kvStore.VerifyEmailSubscription(ctx, email)
+}
+
+func (s *Datastore) RotateSubscriptionToken(
+	ctx context.Context,
+	email string,
+	subscriptionToken string,
+	previousTokenIssuedUnix int64,
+	tokenIssuedUnix int64,
+	tokenExpiryUnix int64,
+) error {
+	return s.kvStore.RotateSubscriptionToken(ctx, email, subscriptionToken, previousTokenIssuedUnix, tokenIssuedUnix, tokenExpiryUnix)
+}
+
+// SetUnsubscribeToken stores the unsubscribe token of a subscription that doesn't have one yet
+func (s *Datastore) SetUnsubscribeToken(ctx context.Context, email string, unsubscribeToken string) error {
+	return s.kvStore.SetUnsubscribeToken(ctx, email, unsubscribeToken)
+}
+
+func (s *Datastore) UnsubscribeEmailSubscription(ctx context.Context, email string, unsubscribeDateUnix int64) error {
+	return s.kvStore.UnsubscribeEmailSubscription(ctx, email, unsubscribeDateUnix)
+}
+
+func (s *Datastore) AddComplaintToEmailSubscription(ctx context.Context, email, complaintDetails string, complaintDateUnix int64) error {
+	return s.kvStore.AddComplaintToEmailSubscription(ctx, email, complaintDetails, complaintDateUnix)
+}
+
+func (s *Datastore) AddBounceToEmailSubscription(ctx context.Context, email, bounceType, bounceDetails string, bounceDateUnix int64) error {
+	return s.kvStore.AddBounceToEmailSubscription(ctx, email, bounceType, bounceDetails, bounceDateUnix)
+}
+
+func (s *Datastore) GetEmailSubscription(ctx context.Context, email string) (*models.EmailSubscription, error) {
+	return s.kvStore.GetEmailSubscription(ctx, email)
+}
+
+func (s *Datastore) DeleteEmailSubscription(ctx context.Context, email string) error {
+	return s.kvStore.DeleteEmailSubscriptionItem(ctx, email)
+}
+
+// ListEmailSubscriptions returns up to limit subscriptions matching the filter, starting after the
+// cursor of the previous page, or at the beginning when the cursor is empty
+func (s *Datastore) ListEmailSubscriptions(ctx context.Context, filter models.EmailSubscriptionFilter, cursor string, limit int) (*models.EmailSubscriptionPage, error) {
+	return s.kvStore.ListEmailSubscriptions(ctx, filter, cursor, limit)
+}
+
+func (s *Datastore) CreateUser(ctx context.Context, email, cognitoUserName string) error {
+	return s.relationalDB.CreateUser(ctx, email, cognitoUserName)
+}
+
+func (s *Datastore) GetUserById(ctx context.Context, id string) (*models.User, error) {
+	return s.relationalDB.GetUserById(ctx, id)
+}
+
+func (s *Datastore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.relationalDB.GetUserByEmail(ctx, email)
 }
 
-func (s *Datastore) VerifyEmailSubscription(email string) error {
-	return s.kvStore.VerifyEmailSubscription(email)
+func (s *Datastore) GetUserByCognitoUserName(ctx context.Context, cognitoUserName string) (*models.User, error) {
+	return s.relationalDB.GetUserByCognitoUserName(ctx, cognitoUserName)
 }
 
-func (s *Datastore) AddComplaintToEmailSubscription(email, complaintDetails string, complaintDateUnix int64) error {
-	return s.kvStore.AddComplaintToEmailSubscription(email, complaintDetails, complaintDateUnix)
+func (s *Datastore) UpdateUserEmail(ctx context.Context, id, email string) error {
+	return s.relationalDB.UpdateUserEmail(ctx, id, email)
 }
 
-func (s *Datastore) AddBounceToEmailSubscription(email, bounceType, bounceDetails string, bounceDateUnix int64) error {
-	return s.kvStore.AddBounceToEmailSubscription(email, bounceType, bounceDetails, bounceDateUnix)
+func (s *Datastore) SoftDeleteUser(ctx context.Context, id string) error {
+	return s.relationalDB.SoftDeleteUser(ctx, id)
 }
 
-func (s *Datastore) GetEmailSubscription(email string) (*models.EmailSubscription, error) {
-	return s.kvStore.GetEmailSubscription(email)
+func (s *Datastore) DeleteUser(ctx context.Context, id string) error {
+	return s.relationalDB.DeleteUser(ctx, id)
 }
 
-func (s *Datastore) CreateUser(email, cognitoUserName string) error {
-	return s.relationalDB.CreateUser(email, cognitoUserName)
+func (s *Datastore) DeleteUserByEmail(ctx context.Context, email string) error {
+	return s.relationalDB.DeleteUserByEmail(ctx, email)
 }
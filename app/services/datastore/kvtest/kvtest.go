@@ -0,0 +1,218 @@
+// Package kvtest is a conformance suite that every datastore.KeyValueStore implementation must pass
+package kvtest
+
+import (
+	"context"
+	"fmt"
+	"score/app/models"
+	"score/app/services/datastore"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Run runs the suite against the store returned by newStore. Stores may be shared between tests,
+// so each test works on its own email address.
+func Run(t *testing.T, newStore func(t *testing.T) datastore.KeyValueStore) {
+	ctx := context.Background()
+	prefix := fmt.Sprintf("kvtest-%d", time.Now().UnixNano())
+
+	t.Run("MissingSubscription", func(t *testing.T) {
+		store := newStore(t)
+		email := prefix + "-missing@example.com"
+		subscription, err := store.GetEmailSubscription(ctx, email)
+		require.NoError(t, err, "Getting a missing subscription should succeed")
+		require.Nil(t, subscription, "A missing subscription should be nil")
+		require.NoError(t, store.DeleteEmailSubscriptionItem(ctx, email), "Deleting a missing subscription should succeed")
+	})
+
+	t.Run("UpdatesRequireSubscription", func(t *testing.T) {
+		store := newStore(t)
+		email := prefix + "-phantom@example.com"
+		require.ErrorIs(t, store.VerifyEmailSubscription(ctx, email), models.ErrEmailSubscriptionNotFound, "Verifying a missing subscription should fail")
+		require.ErrorIs(t, store.RotateSubscriptionToken(ctx, email, "token", 0, 100, 200), models.ErrEmailSubscriptionNotFound, "Rotating the token of a missing subscription should fail")
+		require.ErrorIs(t, store.SetUnsubscribeToken(ctx, email, "unsubscribe"), models.ErrEmailSubscriptionNotFound, "Setting the unsubscribe token of a missing subscription should fail")
+		require.ErrorIs(t, store.UnsubscribeEmailSubscription(ctx, email, 100), models.ErrEmailSubscriptionNotFound, "Unsubscribing a missing subscription should fail")
+		require.ErrorIs(t, store.AddBounceToEmailSubscription(ctx, email, "Permanent", "details", 100), models.ErrEmailSubscriptionNotFound, "Adding a bounce to a missing subscription should fail")
+		require.ErrorIs(t, store.AddComplaintToEmailSubscription(ctx, email, "details", 100), models.ErrEmailSubscriptionNotFound, "Adding a complaint to a missing subscription should fail")
+		subscription, err := store.GetEmailSubscription(ctx, email)
+		require.NoError(t, err, "Getting a missing subscription should succeed")
+		require.Nil(t, subscription, "Failed updates should not create a subscription")
+	})
+
+	t.Run("ConcurrentCreates", func(t *testing.T) {
+		store := newStore(t)
+		email := prefix + "-concurrent@example.com"
+		const creates = 10
+		var wg sync.WaitGroup
+		errs := make(chan error, creates)
+		for i := 0; i < creates; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs <- store.CreateEmailSubscriptionItem(ctx, email, fmt.Sprintf("token-%d", i), "unsubscribe", 100, 200, false)
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		succeeded := 0
+		for err := range errs {
+			if err == nil {
+				succeeded++
+				continue
+			}
+			require.ErrorIs(t, err, models.ErrEmailSubscriptionExists, "Losing creates should fail with ErrEmailSubscriptionExists")
+		}
+		require.Equal(t, 1, succeeded, "Exactly one concurrent create should succeed")
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		store := newStore(t)
+		email := prefix + "-create@example.com"
+		require.NoError(t, store.CreateEmailSubscriptionItem(ctx, email, "token", "unsubscribe", 100, 200, false), "Creating a subscription should succeed")
+		subscription, err := store.GetEmailSubscription(ctx, email)
+		require.NoError(t, err, "Getting a subscription should succeed")
+		require.NotNil(t, subscription, "A created subscription should be returned")
+		require.Equal(t, email, subscription.Email, "The email should be stored")
+		require.Equal(t, "token", subscription.SubscriptionToken, "The subscription token should be stored")
+		require.Equal(t, int64(100), subscription.SubscriptionTokenIssuedUnix, "The token issue date should be stored")
+		require.Equal(t, int64(200), subscription.SubscriptionTokenExpiryUnix, "The token expiry should be stored")
+		require.Equal(t, "unsubscribe", subscription.UnsubscribeToken, "The unsubscribe token should be stored")
+		require.False(t, subscription.Verified, "The subscription should not be verified")
+		require.NotZero(t, subscription.CreationDate, "The creation date should be set")
+
+		err = store.CreateEmailSubscriptionItem(ctx, email, "other", "other", 300, 400, true)
+		require.ErrorIs(t, err, models.ErrEmailSubscriptionExists, "Creating an existing subscription should fail")
+		subscription, err = store.GetEmailSubscription(ctx, email)
+		require.NoError(t, err, "Getting a subscription should succeed")
+		require.Equal(t, "token", subscription.SubscriptionToken, "A failed create should not overwrite the subscription")
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		store := newStore(t)
+		email := prefix + "-verify@example.com"
+		require.NoError(t, store.CreateEmailSubscriptionItem(ctx, email, "token", "unsubscribe", 100, 200, false), "Creating a subscription should succeed")
+		require.NoError(t, store.VerifyEmailSubscription(ctx, email), "Verifying a subscription should succeed")
+		subscription, err := store.GetEmailSubscription(ctx, email)
+		require.NoError(t, err, "Getting a subscription should succeed")
+		require.True(t, subscription.Verified, "The subscription should be verified")
+		require.Empty(t, subscription.SubscriptionToken, "Verifying should remove the subscription token")
+		require.Zero(t, subscription.SubscriptionTokenExpiryUnix, "Verifying should remove the token expiry")
+		require.Equal(t, "unsubscribe", subscription.UnsubscribeToken, "Verifying should keep the unsubscribe token")
+	})
+
+	t.Run("RotateToken", func(t *testing.T) {
+		store := newStore(t)
+		email := prefix + "-rotate@example.com"
+		require.NoError(t, store.CreateEmailSubscriptionItem(ctx, email, "token", "unsubscribe", 100, 200, false), "Creating a subscription should succeed")
+		require.NoError(t, store.RotateSubscriptionToken(ctx, email, "rotated", 100, 300, 400), "Rotating the token should succeed")
+		require.ErrorIs(t, store.RotateSubscriptionToken(ctx, email, "stale", 100, 300, 400), models.ErrEmailSubscriptionChanged, "Rotating a token that was already rotated should fail")
+		subscription, err := store.GetEmailSubscription(ctx, email)
+		require.NoError(t, err, "Getting a subscription should succeed")
+		require.Equal(t, "rotated", subscription.SubscriptionToken, "The token should be rotated")
+		require.Equal(t, int64(300), subscription.SubscriptionTokenIssuedUnix, "The token issue date should be updated")
+		require.Equal(t, int64(400), subscription.SubscriptionTokenExpiryUnix, "The token expiry should be updated")
+	})
+
+	t.Run("BounceAndComplaint", func(t *testing.T) {
+		store := newStore(t)
+		email := prefix + "-bounce@example.com"
+		require.NoError(t, store.CreateEmailSubscriptionItem(ctx, email, "token", "unsubscribe", 100, 200, true), "Creating a subscription should succeed")
+		require.NoError(t, store.AddBounceToEmailSubscription(ctx, email, "Permanent", "mailbox does not exist", 500), "Adding a bounce should succeed")
+		require.NoError(t, store.AddComplaintToEmailSubscription(ctx, email, "abuse", 600), "Adding a complaint should succeed")
+		subscription, err := store.GetEmailSubscription(ctx, email)
+		require.NoError(t, err, "Getting a subscription should succeed")
+		require.True(t, subscription.HasBounce, "The bounce should be recorded")
+		require.Equal(t, "Permanent", subscription.BounceType, "The bounce type should be stored")
+		require.Equal(t, "mailbox does not exist", subscription.BounceDetails, "The bounce details should be stored")
+		require.Equal(t, int64(500), subscription.BounceDateUnix, "The bounce date should be stored")
+		require.True(t, subscription.HasComplaint, "The complaint should be recorded")
+		require.Equal(t, "abuse", subscription.ComplaintDetails, "The complaint details should be stored")
+		require.Equal(t, int64(600), subscription.ComplaintDateUnix, "The complaint date should be stored")
+		require.True(t, subscription.Verified, "Updates should keep the other attributes")
+	})
+
+	t.Run("SetUnsubscribeToken", func(t *testing.T) {
+		store := newStore(t)
+		email := prefix + "-unsubscribe-token@example.com"
+		require.NoError(t, store.CreateEmailSubscriptionItem(ctx, email, "token", "", 100, 200, true), "Creating a subscription without an unsubscribe token should succeed")
+		require.NoError(t, store.SetUnsubscribeToken(ctx, email, "unsubscribe"), "Setting a missing unsubscribe token should succeed")
+		require.ErrorIs(t, store.SetUnsubscribeToken(ctx, email, "other"), models.ErrEmailSubscriptionChanged, "Replacing an unsubscribe token should fail")
+		subscription, err := store.GetEmailSubscription(ctx, email)
+		require.NoError(t, err, "Getting a subscription should succeed")
+		require.Equal(t, "unsubscribe", subscription.UnsubscribeToken, "The first unsubscribe token should be kept")
+	})
+
+	t.Run("UnsubscribeAndDelete", func(t *testing.T) {
+		store := newStore(t)
+		email := prefix + "-unsubscribe@example.com"
+		require.NoError(t, store.CreateEmailSubscriptionItem(ctx, email, "token", "unsubscribe", 100, 200, true), "Creating a subscription should succeed")
+		require.NoError(t, store.UnsubscribeEmailSubscription(ctx, email, 700), "Unsubscribing should succeed")
+		subscription, err := store.GetEmailSubscription(ctx, email)
+		require.NoError(t, err, "Getting a subscription should succeed")
+		require.True(t, subscription.Unsubscribed, "The subscription should be unsubscribed")
+		require.Equal(t, int64(700), subscription.UnsubscribeDateUnix, "The unsubscribe date should be stored")
+		require.NoError(t, store.DeleteEmailSubscriptionItem(ctx, email), "Deleting a subscription should succeed")
+		subscription, err = store.GetEmailSubscription(ctx, email)
+		require.NoError(t, err, "Getting a deleted subscription should succeed")
+		require.Nil(t, subscription, "A deleted subscription should not be returned")
+	})
+
+	t.Run("List", func(t *testing.T) {
+		store := newStore(t)
+		listPrefix := prefix + "-list-"
+		for i := 0; i < 5; i++ {
+			email := fmt.Sprintf("%s%d@example.com", listPrefix, i)
+			require.NoError(t, store.CreateEmailSubscriptionItem(ctx, email, "token", "unsubscribe", 100, 200, i%2 == 0), "Creating a subscription should succeed")
+		}
+		require.NoError(t, store.AddBounceToEmailSubscription(ctx, listPrefix+"0@example.com", "Permanent", "details", 100), "Adding a bounce should succeed")
+
+		// Other tests may share the store, so only the subscriptions of this test are looked at
+		listAll := func(filter models.EmailSubscriptionFilter) []string {
+			emails := []string{}
+			cursor := ""
+			for pages := 0; ; pages++ {
+				require.Less(t, pages, 1000, "Listing should reach the last page")
+				page, err := store.ListEmailSubscriptions(ctx, filter, cursor, 2)
+				require.NoError(t, err, "Listing subscriptions should succeed")
+				require.LessOrEqual(t, len(page.Subscriptions), 2, "Pages should not exceed the limit")
+				for _, subscription := range page.Subscriptions {
+					require.True(t, filter.Matches(&subscription), "Listed subscriptions should match the filter")
+					if strings.HasPrefix(subscription.Email, listPrefix) {
+						emails = append(emails, subscription.Email)
+					}
+				}
+				if page.NextCursor == "" {
+					return emails
+				}
+				cursor = page.NextCursor
+			}
+		}
+
+		require.ElementsMatch(t, []string{
+			listPrefix + "0@example.com",
+			listPrefix + "1@example.com",
+			listPrefix + "2@example.com",
+			listPrefix + "3@example.com",
+			listPrefix + "4@example.com",
+		}, listAll(models.EmailSubscriptionFilter{}), "Paging should list every subscription once")
+
+		verifiedFilter := models.EmailSubscriptionFilter{}
+		require.NoError(t, verifiedFilter.Set(models.VerifiedFilter, "true"), "Setting a filter should succeed")
+		require.NoError(t, verifiedFilter.Set(models.BouncedFilter, "false"), "Setting a filter should succeed")
+		require.ElementsMatch(t, []string{
+			listPrefix + "2@example.com",
+			listPrefix + "4@example.com",
+		}, listAll(verifiedFilter), "Filters should narrow the listing")
+
+		_, err := store.ListEmailSubscriptions(ctx, models.EmailSubscriptionFilter{}, "not a cursor", 2)
+		require.ErrorIs(t, err, models.ErrInvalidCursor, "Malformed cursors should be rejected")
+		for _, limit := range []int{0, -1} {
+			_, err = store.ListEmailSubscriptions(ctx, models.EmailSubscriptionFilter{}, "", limit)
+			require.ErrorIs(t, err, models.ErrInvalidPageLimit, "Limits that aren't positive should be rejected")
+		}
+	})
+}
@@ -1,13 +1,28 @@
 package email
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"net/mail"
+	"score/app/logger"
 	"score/app/models"
 	"strings"
+	"time"
+)
+
+const (
+	// subscriptionTokenLifetime is how long a verification link stays valid after it's issued
+	subscriptionTokenLifetime = 48 * time.Hour
+	// verificationResendCooldown is the minimum time between two verification emails to the same address
+	verificationResendCooldown = 5 * time.Minute
+	// DefaultEmailSubscriptionPageSize is the page size of listings that don't ask for one
+	DefaultEmailSubscriptionPageSize = 100
+	// MaxEmailSubscriptionPageSize caps the page size of listings
+	MaxEmailSubscriptionPageSize = 1000
 )
 
 type EmailService struct {
@@ -28,24 +43,29 @@ func New(emailSender EmailSender, datastore Datastore, logger Logger, eventPubli
 
 type EmailSender interface {
 	SendEmail(
+		ctx context.Context,
 		senderAddress string,
 		toAddresses []string,
 		subjectLine string,
 		emailBody string,
+		headers map[string]string,
 	) error
 }
 
 type PlatformEventPublisher interface {
-	PublishEmailVerificationTask(email, token string) error
+	PublishEmailVerificationTask(ctx context.Context, email, token, unsubscribeToken string) error
 }
 
 type Datastore interface {
-	EmailSubscriptionExists(email string) (bool, error)
-	AddComplaintToEmailSubscription(email, complaintDetails string, complaintDateUnix int64) error
-	AddBounceToEmailSubscription(email, bounceType, bounceDetails string, bounceDateUnix int64) error
-	GetEmailSubscription(email string) (*models.EmailSubscription, error)
-	CreateEmailSubscription(email string, subscriptionToken string, isVerified bool) error
-	VerifyEmailSubscription(email string) error
+	AddComplaintToEmailSubscription(ctx context.Context, email, complaintDetails string, complaintDateUnix int64) error
+	AddBounceToEmailSubscription(ctx context.Context, email, bounceType, bounceDetails string, bounceDateUnix int64) error
+	GetEmailSubscription(ctx context.Context, email string) (*models.EmailSubscription, error)
+	ListEmailSubscriptions(ctx context.Context, filter models.EmailSubscriptionFilter, cursor string, limit int) (*models.EmailSubscriptionPage, error)
+	CreateEmailSubscription(ctx context.Context, email string, subscriptionToken, unsubscribeToken string, tokenIssuedUnix, tokenExpiryUnix int64, isVerified bool) error
+	VerifyEmailSubscription(ctx context.Context, email string) error
+	RotateSubscriptionToken(ctx context.Context, email string, subscriptionToken string, previousTokenIssuedUnix, tokenIssuedUnix, tokenExpiryUnix int64) error
+	SetUnsubscribeToken(ctx context.Context, email string, unsubscribeToken string) error
+	UnsubscribeEmailSubscription(ctx context.Context, email string, unsubscribeDateUnix int64) error
 }
 
 type Logger interface {
@@ -55,62 +75,65 @@ type Logger interface {
 }
 
 func (s *EmailService) SendTemplatedEmail(
+	ctx context.Context,
 	templateName string,
 	templateParams map[string]string,
 	subjectLine string,
 	senderAddress string,
 	toAddresses []string,
+	headers map[string]string,
 ) error {
-	// Get email subscriptions and filter out email addresses that have complaints or bounces
+	requestLogger := logger.FromContext(ctx, s.logger)
+	// Get email subscriptions and filter out email addresses that have unsubscribed, complaints or bounces
 	filteredToAddresses := []string{}
 	for _, email := range toAddresses {
-		emailSubscription, err := s.datastore.GetEmailSubscription(email)
+		emailSubscription, err := s.datastore.GetEmailSubscription(ctx, email)
 		if err != nil {
-			s.logger.ErrorWithContext("error getting email subscription", "email", email, "error", err.Error())
+			requestLogger.ErrorWithContext("error getting email subscription", "email", email, "error", err.Error())
 			return err
 		} else if emailSubscription == nil {
-			s.logger.InfoWithContext("email subscription not found. excluding from toAddresses", "email", email)
+			requestLogger.InfoWithContext("email subscription not found. excluding from toAddresses", "email", email)
+			continue
+		} else if emailSubscription.Unsubscribed {
+			requestLogger.InfoWithContext("email subscription is unsubscribed. excluding from toAddresses", "email", email)
 			continue
 		} else if emailSubscription.HasComplaint {
-			s.logger.InfoWithContext("email subscription has complaint. excluding from toAddresses", "email", email)
+			requestLogger.InfoWithContext("email subscription has complaint. excluding from toAddresses", "email", email)
 			continue
 		} else if emailSubscription.BounceType == "Permanent" {
-			s.logger.InfoWithContext("email subscription has permanent bounce. excluding from toAddresses", "email", email)
+			requestLogger.InfoWithContext("email subscription has permanent bounce. excluding from toAddresses", "email", email)
 			continue
 		} else {
 			filteredToAddresses = append(filteredToAddresses, email)
 		}
 	}
 	if len(filteredToAddresses) == 0 {
-		s.logger.InfoWithContext("no email addresses to send to after filtering", "templateName", templateName)
+		requestLogger.InfoWithContext("no email addresses to send to after filtering", "templateName", templateName)
 		return nil
 	}
 	emailBody, err := generateEmailBodyFromTemplate(templateName, templateParams)
 	if err != nil {
 		return fmt.Errorf("error while generating email body => %v", err.Error())
 	}
-	return s.emailSender.SendEmail(senderAddress, filteredToAddresses, subjectLine, emailBody)
+	return s.emailSender.SendEmail(ctx, senderAddress, filteredToAddresses, subjectLine, emailBody, headers)
 }
 
 func (s *EmailService) ProcessEmailComplaint(
+	ctx context.Context,
 	complainedEmailAddresses []string,
 	complaintDetails string,
 	complaintUnixTime int64,
 ) error {
 	for _, email := range complainedEmailAddresses {
-		exists, err := s.datastore.EmailSubscriptionExists(email)
-		if err != nil {
-			return fmt.Errorf("error checking if email subscription item exists => %v", err.Error())
-		}
-		if exists {
-			err := s.datastore.AddComplaintToEmailSubscription(
-				email,
-				complaintDetails,
-				complaintUnixTime,
-			)
-			if err != nil {
-				return fmt.Errorf("error while adding complaint to email subscription => %v", err.Error())
-			}
+		err := s.datastore.AddComplaintToEmailSubscription(
+			ctx,
+			email,
+			complaintDetails,
+			complaintUnixTime,
+		)
+		// Complaints about addresses without a subscription are ignored
+		if err != nil && !errors.Is(err, models.ErrEmailSubscriptionNotFound) {
+			return fmt.Errorf("error while adding complaint to email subscription => %v", err.Error())
 		}
 	}
 	return nil
@@ -118,6 +141,7 @@ func (s *EmailService) ProcessEmailComplaint(
 
 // ProcessEmailBounce processes an email bounce event
 func (s *EmailService) ProcessEmailBounce(
+	ctx context.Context,
 	bouncedEmailAddresses []string,
 	bounceType,
 	bounceSubType,
@@ -125,26 +149,22 @@ func (s *EmailService) ProcessEmailBounce(
 	bounceUnixTime int64,
 ) error {
 	for _, email := range bouncedEmailAddresses {
-		exists, err := s.datastore.EmailSubscriptionExists(email)
-		if err != nil {
-			return fmt.Errorf("error checking if email subscription item exists => %v", err.Error())
+		savedBounceType := ""
+		if bounceType == "Permanent" {
+			savedBounceType = "Permanent"
+		} else {
+			savedBounceType = bounceSubType
 		}
-		if exists {
-			savedBounceType := ""
-			if bounceType == "Permanent" {
-				savedBounceType = "Permanent"
-			} else {
-				savedBounceType = bounceSubType
-			}
-			err := s.datastore.AddBounceToEmailSubscription(
-				email,
-				savedBounceType,
-				bounceDetails,
-				bounceUnixTime,
-			)
-			if err != nil {
-				return fmt.Errorf("error while adding bounce to email subscription => %v", err.Error())
-			}
+		err := s.datastore.AddBounceToEmailSubscription(
+			ctx,
+			email,
+			savedBounceType,
+			bounceDetails,
+			bounceUnixTime,
+		)
+		// Bounces of addresses without a subscription are ignored
+		if err != nil && !errors.Is(err, models.ErrEmailSubscriptionNotFound) {
+			return fmt.Errorf("error while adding bounce to email subscription => %v", err.Error())
 		}
 	}
 	return nil
@@ -155,41 +175,173 @@ func (s *EmailService) IsValidEmail(email string) bool {
 	return err == nil
 }
 
-func (s *EmailService) EmailSubscriptionExists(email string) (bool, error) {
-	return s.datastore.EmailSubscriptionExists(email)
+// ListEmailSubscriptions returns a page of the subscriptions matching the filter. A limit that isn't
+// positive means the default page size, and limits above the maximum page size are capped.
+func (s *EmailService) ListEmailSubscriptions(ctx context.Context, filter models.EmailSubscriptionFilter, cursor string, limit int) (*models.EmailSubscriptionPage, error) {
+	if limit <= 0 {
+		limit = DefaultEmailSubscriptionPageSize
+	}
+	if limit > MaxEmailSubscriptionPageSize {
+		limit = MaxEmailSubscriptionPageSize
+	}
+	page, err := s.datastore.ListEmailSubscriptions(ctx, filter, cursor, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error listing email subscriptions: %w", err)
+	}
+	return page, nil
+}
+
+// CreateEmailSubscription creates an unverified subscription and sends the verification email. It
+// fails with models.ErrEmailSubscriptionExists when the email already has a subscription.
+func (s *EmailService) CreateEmailSubscription(ctx context.Context, email string) error {
+	subscriptionToken, err := generateEmailSubscriptionToken(email)
+	if err != nil {
+		return fmt.Errorf("error generating email subscription token: %v", err)
+	}
+	unsubscribeToken, err := generateEmailSubscriptionToken(email)
+	if err != nil {
+		return fmt.Errorf("error generating unsubscribe token: %v", err)
+	}
+	tokenIssued := time.Now()
+	tokenExpiry := tokenIssued.Add(subscriptionTokenLifetime)
+	err = s.datastore.CreateEmailSubscription(ctx, email, subscriptionToken, unsubscribeToken, tokenIssued.Unix(), tokenExpiry.Unix(), false)
+	if err != nil {
+		return fmt.Errorf("error creating email subscription in datastore: %w", err)
+	}
+	err = s.eventPublisher.PublishEmailVerificationTask(ctx, email, subscriptionToken, unsubscribeToken)
+	if err != nil {
+		return fmt.Errorf("error publishing email verification task: %v", err)
+	}
+	return nil
+}
+
+func (s *EmailService) VerifyEmailWithSubscriptionToken(ctx context.Context, token string) error {
+	email, err := parseEmailFromSubscriptionToken(token)
+	if err != nil {
+		return fmt.Errorf("%w: %v", models.ErrInvalidSubscriptionToken, err)
+	}
+	emailSubscription, err := s.datastore.GetEmailSubscription(ctx, email)
+	if err != nil {
+		return fmt.Errorf("error getting email subscription: %v", err)
+	}
+	if emailSubscription == nil {
+		return fmt.Errorf("%w: %v", models.ErrEmailSubscriptionNotFound, email)
+	}
+	if !subscriptionTokensMatch(emailSubscription.SubscriptionToken, token) {
+		return fmt.Errorf("%w: token does not match stored token for %v", models.ErrInvalidSubscriptionToken, email)
+	}
+	// Subscriptions created before tokens expired have no expiry, and their tokens are treated as
+	// expired so that the user asks for a new verification email
+	if emailSubscription.SubscriptionTokenExpiryUnix == 0 {
+		return fmt.Errorf("%w: token for %v has no expiry, a new verification email must be requested", models.ErrExpiredSubscriptionToken, email)
+	}
+	if emailSubscription.SubscriptionTokenExpiryUnix <= time.Now().Unix() {
+		return fmt.Errorf("%w: token for %v expired at %d", models.ErrExpiredSubscriptionToken, email, emailSubscription.SubscriptionTokenExpiryUnix)
+	}
+	err = s.datastore.VerifyEmailSubscription(ctx, email)
+	if err != nil {
+		return fmt.Errorf("error verifying existing subscription: %w", err)
+	}
+	return nil
 }
 
-func (s *EmailService) CreateEmailSubscription(email string) error {
+// ResendVerificationEmail rotates the subscription token of an unverified subscription and sends a new verification email.
+// A concurrent resend that rotated the token first makes it fail with models.ErrVerificationResendTooSoon.
+func (s *EmailService) ResendVerificationEmail(ctx context.Context, email string) error {
+	emailSubscription, err := s.datastore.GetEmailSubscription(ctx, email)
+	if err != nil {
+		return fmt.Errorf("error getting email subscription: %v", err)
+	}
+	if emailSubscription == nil {
+		return fmt.Errorf("%w: %v", models.ErrEmailSubscriptionNotFound, email)
+	}
+	if emailSubscription.Verified {
+		return fmt.Errorf("%w: %v", models.ErrEmailAlreadyVerified, email)
+	}
+	now := time.Now()
+	lastIssued := time.Unix(emailSubscription.SubscriptionTokenIssuedUnix, 0)
+	if now.Sub(lastIssued) < verificationResendCooldown {
+		return fmt.Errorf("%w: %v", models.ErrVerificationResendTooSoon, email)
+	}
 	subscriptionToken, err := generateEmailSubscriptionToken(email)
 	if err != nil {
 		return fmt.Errorf("error generating email subscription token: %v", err)
 	}
-	err = s.datastore.CreateEmailSubscription(email, subscriptionToken, false)
+	err = s.datastore.RotateSubscriptionToken(
+		ctx,
+		email,
+		subscriptionToken,
+		emailSubscription.SubscriptionTokenIssuedUnix,
+		now.Unix(),
+		now.Add(subscriptionTokenLifetime).Unix(),
+	)
+	if errors.Is(err, models.ErrEmailSubscriptionChanged) {
+		return fmt.Errorf("%w: %v", models.ErrVerificationResendTooSoon, email)
+	}
+	if err != nil {
+		return fmt.Errorf("error rotating subscription token in datastore: %w", err)
+	}
+	unsubscribeToken, err := s.ensureUnsubscribeToken(ctx, emailSubscription)
 	if err != nil {
-		return fmt.Errorf("error creating email subscription in datastore: %v", err)
+		return err
 	}
-	err = s.eventPublisher.PublishEmailVerificationTask(email, subscriptionToken)
+	err = s.eventPublisher.PublishEmailVerificationTask(ctx, email, subscriptionToken, unsubscribeToken)
 	if err != nil {
 		return fmt.Errorf("error publishing email verification task: %v", err)
 	}
 	return nil
 }
 
-func (s *EmailService) VerifyEmailWithSubscriptionToken(token string) error {
+// ensureUnsubscribeToken returns the unsubscribe token of the subscription, generating one for
+// subscriptions created before they had one so that the next email sent to them can be unsubscribed from
+func (s *EmailService) ensureUnsubscribeToken(ctx context.Context, emailSubscription *models.EmailSubscription) (string, error) {
+	if emailSubscription.UnsubscribeToken != "" {
+		return emailSubscription.UnsubscribeToken, nil
+	}
+	unsubscribeToken, err := generateEmailSubscriptionToken(emailSubscription.Email)
+	if err != nil {
+		return "", fmt.Errorf("error generating unsubscribe token: %v", err)
+	}
+	err = s.datastore.SetUnsubscribeToken(ctx, emailSubscription.Email, unsubscribeToken)
+	if errors.Is(err, models.ErrEmailSubscriptionChanged) {
+		// A concurrent send stored a token first, which is the one to use
+		current, err := s.datastore.GetEmailSubscription(ctx, emailSubscription.Email)
+		if err != nil {
+			return "", fmt.Errorf("error getting email subscription: %v", err)
+		}
+		if current == nil {
+			return "", fmt.Errorf("%w: %v", models.ErrEmailSubscriptionNotFound, emailSubscription.Email)
+		}
+		return current.UnsubscribeToken, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error storing unsubscribe token: %w", err)
+	}
+	return unsubscribeToken, nil
+}
+
+// UnsubscribeWithToken marks the subscription matching the unsubscribe token as unsubscribed
+func (s *EmailService) UnsubscribeWithToken(ctx context.Context, token string) error {
 	email, err := parseEmailFromSubscriptionToken(token)
 	if err != nil {
-		return fmt.Errorf("error parsing email from token: %v", err)
+		return fmt.Errorf("%w: %v", models.ErrInvalidSubscriptionToken, err)
 	}
-	subscriptionExists, err := s.datastore.EmailSubscriptionExists(email)
+	emailSubscription, err := s.datastore.GetEmailSubscription(ctx, email)
 	if err != nil {
-		return fmt.Errorf("error checking if email subscription exists: %v", err)
+		return fmt.Errorf("error getting email subscription: %v", err)
+	}
+	if emailSubscription == nil {
+		return fmt.Errorf("%w: %v", models.ErrEmailSubscriptionNotFound, email)
+	}
+	if !subscriptionTokensMatch(emailSubscription.UnsubscribeToken, token) {
+		return fmt.Errorf("%w: token does not match stored unsubscribe token for %v", models.ErrInvalidSubscriptionToken, email)
 	}
-	if !subscriptionExists {
-		return fmt.Errorf("email subscription doesn't exist: %v", email)
+	if emailSubscription.Unsubscribed {
+		return nil
 	}
-	err = s.datastore.VerifyEmailSubscription(email)
+	err = s.datastore.UnsubscribeEmailSubscription(ctx, email, time.Now().Unix())
 	if err != nil {
-		return fmt.Errorf("error verifying existing subscription: %v", err)
+		return fmt.Errorf("error unsubscribing email subscription: %w", err)
 	}
 	return nil
 }
@@ -227,3 +379,11 @@ func parseEmailFromSubscriptionToken(tokenWithPrefix string) (string, error) {
 
 	return string(emailBytes), nil
 }
+
+// subscriptionTokensMatch compares tokens in constant time so that response timing doesn't leak the stored token
+func subscriptionTokensMatch(storedToken, providedToken string) bool {
+	if storedToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(storedToken), []byte(providedToken)) == 1
+}
@@ -0,0 +1,350 @@
+package email
+
+import (
+	"context"
+	"encoding/base64"
+	"score/app/logger"
+	"score/app/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDatastore keeps subscriptions in a map and records the subscriptions that were verified or unsubscribed
+type fakeDatastore struct {
+	subscriptions map[string]*models.EmailSubscription
+	verified      []string
+	unsubscribed  []string
+}
+
+func newFakeDatastore(subscriptions ...models.EmailSubscription) *fakeDatastore {
+	s := &fakeDatastore{subscriptions: map[string]*models.EmailSubscription{}}
+	for i := range subscriptions {
+		s.subscriptions[subscriptions[i].Email] = &subscriptions[i]
+	}
+	return s
+}
+
+func (s *fakeDatastore) AddComplaintToEmailSubscription(ctx context.Context, email, complaintDetails string, complaintDateUnix int64) error {
+	return nil
+}
+
+func (s *fakeDatastore) AddBounceToEmailSubscription(ctx context.Context, email, bounceType, bounceDetails string, bounceDateUnix int64) error {
+	return nil
+}
+
+func (s *fakeDatastore) GetEmailSubscription(ctx context.Context, email string) (*models.EmailSubscription, error) {
+	subscription, ok := s.subscriptions[email]
+	if !ok {
+		return nil, nil
+	}
+	subscriptionCopy := *subscription
+	return &subscriptionCopy, nil
+}
+
+func (s *fakeDatastore) ListEmailSubscriptions(ctx context.Context, filter models.EmailSubscriptionFilter, cursor string, limit int) (*models.EmailSubscriptionPage, error) {
+	return &models.EmailSubscriptionPage{}, nil
+}
+
+func (s *fakeDatastore) CreateEmailSubscription(ctx context.Context, email string, subscriptionToken, unsubscribeToken string, tokenIssuedUnix, tokenExpiryUnix int64, isVerified bool) error {
+	return nil
+}
+
+func (s *fakeDatastore) VerifyEmailSubscription(ctx context.Context, email string) error {
+	s.verified = append(s.verified, email)
+	if subscription, ok := s.subscriptions[email]; ok {
+		subscription.Verified = true
+		subscription.SubscriptionToken = ""
+		subscription.SubscriptionTokenIssuedUnix = 0
+		subscription.SubscriptionTokenExpiryUnix = 0
+	}
+	return nil
+}
+
+func (s *fakeDatastore) RotateSubscriptionToken(ctx context.Context, email string, subscriptionToken string, previousTokenIssuedUnix, tokenIssuedUnix, tokenExpiryUnix int64) error {
+	subscription, ok := s.subscriptions[email]
+	if !ok {
+		return models.ErrEmailSubscriptionNotFound
+	}
+	if subscription.SubscriptionTokenIssuedUnix != previousTokenIssuedUnix {
+		return models.ErrEmailSubscriptionChanged
+	}
+	subscription.SubscriptionToken = subscriptionToken
+	subscription.SubscriptionTokenIssuedUnix = tokenIssuedUnix
+	subscription.SubscriptionTokenExpiryUnix = tokenExpiryUnix
+	return nil
+}
+
+func (s *fakeDatastore) SetUnsubscribeToken(ctx context.Context, email string, unsubscribeToken string) error {
+	subscription, ok := s.subscriptions[email]
+	if !ok {
+		return models.ErrEmailSubscriptionNotFound
+	}
+	if subscription.UnsubscribeToken != "" {
+		return models.ErrEmailSubscriptionChanged
+	}
+	subscription.UnsubscribeToken = unsubscribeToken
+	return nil
+}
+
+func (s *fakeDatastore) UnsubscribeEmailSubscription(ctx context.Context, email string, unsubscribeDateUnix int64) error {
+	s.unsubscribed = append(s.unsubscribed, email)
+	if subscription, ok := s.subscriptions[email]; ok {
+		subscription.Unsubscribed = true
+		subscription.UnsubscribeDateUnix = unsubscribeDateUnix
+	}
+	return nil
+}
+
+// fakePublisher records the tokens of the verification tasks it publishes
+type fakePublisher struct {
+	tokens            []string
+	unsubscribeTokens []string
+}
+
+func (p *fakePublisher) PublishEmailVerificationTask(ctx context.Context, email, token, unsubscribeToken string) error {
+	p.tokens = append(p.tokens, token)
+	p.unsubscribeTokens = append(p.unsubscribeTokens, unsubscribeToken)
+	return nil
+}
+
+// fakeSender records the recipients of the emails it sends
+type fakeSender struct {
+	toAddresses [][]string
+}
+
+func (s *fakeSender) SendEmail(ctx context.Context, senderAddress string, toAddresses []string, subjectLine string, emailBody string, headers map[string]string) error {
+	s.toAddresses = append(s.toAddresses, toAddresses)
+	return nil
+}
+
+func TestVerifyEmailWithSubscriptionToken(t *testing.T) {
+	ctx := context.Background()
+	token, err := generateEmailSubscriptionToken("a@example.com")
+	require.NoError(t, err, "Generating a token should succeed")
+	expiry := time.Now().Add(time.Hour).Unix()
+
+	t.Run("forged token", func(t *testing.T) {
+		datastore := newFakeDatastore(models.EmailSubscription{Email: "a@example.com", SubscriptionToken: token, SubscriptionTokenExpiryUnix: expiry})
+		service := New(nil, datastore, logger.NewNop(), nil)
+		forgedToken := base64.StdEncoding.EncodeToString([]byte("a@example.com")) + ":anything"
+		err := service.VerifyEmailWithSubscriptionToken(ctx, forgedToken)
+		require.ErrorIs(t, err, models.ErrInvalidSubscriptionToken, "A token that doesn't match the stored one should be rejected")
+		require.Empty(t, datastore.verified, "A forged token should not verify the subscription")
+	})
+
+	t.Run("missing subscription", func(t *testing.T) {
+		datastore := newFakeDatastore()
+		service := New(nil, datastore, logger.NewNop(), nil)
+		err := service.VerifyEmailWithSubscriptionToken(ctx, token)
+		require.ErrorIs(t, err, models.ErrEmailSubscriptionNotFound, "A token for an unknown email should report the subscription as missing")
+	})
+
+	t.Run("empty stored token", func(t *testing.T) {
+		datastore := newFakeDatastore(models.EmailSubscription{Email: "a@example.com", SubscriptionTokenExpiryUnix: expiry})
+		service := New(nil, datastore, logger.NewNop(), nil)
+		err := service.VerifyEmailWithSubscriptionToken(ctx, base64.StdEncoding.EncodeToString([]byte("a@example.com"))+":")
+		require.ErrorIs(t, err, models.ErrInvalidSubscriptionToken, "A subscription without a stored token should reject every token")
+		require.Empty(t, datastore.verified, "A subscription without a stored token should not be verified")
+	})
+
+	t.Run("matching token", func(t *testing.T) {
+		datastore := newFakeDatastore(models.EmailSubscription{Email: "a@example.com", SubscriptionToken: token, SubscriptionTokenExpiryUnix: expiry})
+		service := New(nil, datastore, logger.NewNop(), nil)
+		require.NoError(t, service.VerifyEmailWithSubscriptionToken(ctx, token), "The stored token should verify the subscription")
+		require.Equal(t, []string{"a@example.com"}, datastore.verified, "The subscription should be verified")
+	})
+}
+
+func TestVerifyEmailWithSubscriptionTokenExpiry(t *testing.T) {
+	ctx := context.Background()
+	token, err := generateEmailSubscriptionToken("a@example.com")
+	require.NoError(t, err, "Generating a token should succeed")
+
+	t.Run("expired token", func(t *testing.T) {
+		datastore := newFakeDatastore(models.EmailSubscription{Email: "a@example.com", SubscriptionToken: token, SubscriptionTokenExpiryUnix: time.Now().Add(-time.Minute).Unix()})
+		service := New(nil, datastore, logger.NewNop(), nil)
+		err := service.VerifyEmailWithSubscriptionToken(ctx, token)
+		require.ErrorIs(t, err, models.ErrExpiredSubscriptionToken, "A token past its expiry should be rejected")
+		require.Empty(t, datastore.verified, "An expired token should not verify the subscription")
+	})
+
+	t.Run("token without expiry", func(t *testing.T) {
+		datastore := newFakeDatastore(models.EmailSubscription{Email: "a@example.com", SubscriptionToken: token})
+		service := New(nil, datastore, logger.NewNop(), nil)
+		err := service.VerifyEmailWithSubscriptionToken(ctx, token)
+		require.ErrorIs(t, err, models.ErrExpiredSubscriptionToken, "A token without an expiry should be treated as expired")
+		require.ErrorContains(t, err, "new verification email", "The error should tell the user to request a new verification email")
+		require.Empty(t, datastore.verified, "A token without an expiry should not verify the subscription")
+	})
+
+	t.Run("single use", func(t *testing.T) {
+		datastore := newFakeDatastore(models.EmailSubscription{Email: "a@example.com", SubscriptionToken: token, SubscriptionTokenExpiryUnix: time.Now().Add(time.Hour).Unix()})
+		service := New(nil, datastore, logger.NewNop(), nil)
+		require.NoError(t, service.VerifyEmailWithSubscriptionToken(ctx, token), "The first use of the token should verify the subscription")
+		err := service.VerifyEmailWithSubscriptionToken(ctx, token)
+		require.ErrorIs(t, err, models.ErrInvalidSubscriptionToken, "A token should not be usable twice")
+		require.Len(t, datastore.verified, 1, "The subscription should be verified once")
+	})
+}
+
+func TestResendVerificationEmail(t *testing.T) {
+	ctx := context.Background()
+	email := "a@example.com"
+
+	t.Run("cooldown", func(t *testing.T) {
+		issued := time.Now().Add(-time.Minute).Unix()
+		datastore := newFakeDatastore(models.EmailSubscription{Email: email, SubscriptionToken: "token", SubscriptionTokenIssuedUnix: issued})
+		publisher := &fakePublisher{}
+		service := New(nil, datastore, logger.NewNop(), publisher)
+		err := service.ResendVerificationEmail(ctx, email)
+		require.ErrorIs(t, err, models.ErrVerificationResendTooSoon, "A resend within the cooldown should be rejected")
+		require.Equal(t, "token", datastore.subscriptions[email].SubscriptionToken, "A rejected resend should keep the token")
+		require.Empty(t, publisher.tokens, "A rejected resend should not send an email")
+	})
+
+	t.Run("after cooldown", func(t *testing.T) {
+		issued := time.Now().Add(-verificationResendCooldown - time.Minute).Unix()
+		datastore := newFakeDatastore(models.EmailSubscription{Email: email, SubscriptionToken: "token", SubscriptionTokenIssuedUnix: issued})
+		publisher := &fakePublisher{}
+		service := New(nil, datastore, logger.NewNop(), publisher)
+		require.NoError(t, service.ResendVerificationEmail(ctx, email), "A resend after the cooldown should succeed")
+		subscription := datastore.subscriptions[email]
+		require.NotEqual(t, "token", subscription.SubscriptionToken, "The token should be rotated")
+		require.Greater(t, subscription.SubscriptionTokenExpiryUnix, time.Now().Unix(), "The rotated token should expire in the future")
+		require.Equal(t, []string{subscription.SubscriptionToken}, publisher.tokens, "The rotated token should be sent")
+	})
+
+	t.Run("concurrent rotation", func(t *testing.T) {
+		issued := time.Now().Add(-verificationResendCooldown - time.Minute).Unix()
+		datastore := newFakeDatastore(models.EmailSubscription{Email: email, SubscriptionToken: "token", SubscriptionTokenIssuedUnix: issued})
+		publisher := &fakePublisher{}
+		service := New(nil, datastore, logger.NewNop(), publisher)
+		// Another resend rotates the token between this one's read and its rotation
+		stale := &staleDatastore{fakeDatastore: datastore}
+		service.datastore = stale
+		err := service.ResendVerificationEmail(ctx, email)
+		require.ErrorIs(t, err, models.ErrVerificationResendTooSoon, "A resend that lost the race should be rejected")
+		require.Empty(t, publisher.tokens, "A resend that lost the race should not send an email")
+	})
+
+	t.Run("verified subscription", func(t *testing.T) {
+		datastore := newFakeDatastore(models.EmailSubscription{Email: email, Verified: true})
+		service := New(nil, datastore, logger.NewNop(), &fakePublisher{})
+		err := service.ResendVerificationEmail(ctx, email)
+		require.ErrorIs(t, err, models.ErrEmailAlreadyVerified, "A verified subscription should not get a new verification email")
+	})
+}
+
+// staleDatastore returns the subscription as it was before a concurrent token rotation
+type staleDatastore struct {
+	*fakeDatastore
+}
+
+func (s *staleDatastore) GetEmailSubscription(ctx context.Context, email string) (*models.EmailSubscription, error) {
+	subscription, err := s.fakeDatastore.GetEmailSubscription(ctx, email)
+	if err != nil || subscription == nil {
+		return subscription, err
+	}
+	s.subscriptions[email].SubscriptionTokenIssuedUnix = time.Now().Unix()
+	return subscription, nil
+}
+
+func TestResendVerificationEmailUnsubscribeToken(t *testing.T) {
+	ctx := context.Background()
+	email := "a@example.com"
+	issued := time.Now().Add(-verificationResendCooldown - time.Minute).Unix()
+
+	t.Run("existing token", func(t *testing.T) {
+		datastore := newFakeDatastore(models.EmailSubscription{Email: email, SubscriptionTokenIssuedUnix: issued, UnsubscribeToken: "unsubscribe"})
+		publisher := &fakePublisher{}
+		service := New(nil, datastore, logger.NewNop(), publisher)
+		require.NoError(t, service.ResendVerificationEmail(ctx, email), "Resending should succeed")
+		require.Equal(t, []string{"unsubscribe"}, publisher.unsubscribeTokens, "The stored unsubscribe token should be sent")
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		datastore := newFakeDatastore(models.EmailSubscription{Email: email, SubscriptionTokenIssuedUnix: issued})
+		publisher := &fakePublisher{}
+		service := New(nil, datastore, logger.NewNop(), publisher)
+		require.NoError(t, service.ResendVerificationEmail(ctx, email), "Resending should succeed")
+		unsubscribeToken := datastore.subscriptions[email].UnsubscribeToken
+		require.NotEmpty(t, unsubscribeToken, "A subscription without an unsubscribe token should get one")
+		require.Equal(t, []string{unsubscribeToken}, publisher.unsubscribeTokens, "The generated unsubscribe token should be sent")
+		require.NoError(t, service.UnsubscribeWithToken(ctx, unsubscribeToken), "The generated unsubscribe token should unsubscribe")
+	})
+}
+
+func TestUnsubscribeWithToken(t *testing.T) {
+	ctx := context.Background()
+	token, err := generateEmailSubscriptionToken("a@example.com")
+	require.NoError(t, err, "Generating a token should succeed")
+
+	t.Run("forged token", func(t *testing.T) {
+		datastore := newFakeDatastore(models.EmailSubscription{Email: "a@example.com", UnsubscribeToken: token})
+		service := New(nil, datastore, logger.NewNop(), nil)
+		forgedToken := base64.StdEncoding.EncodeToString([]byte("a@example.com")) + ":anything"
+		err := service.UnsubscribeWithToken(ctx, forgedToken)
+		require.ErrorIs(t, err, models.ErrInvalidSubscriptionToken, "A token that doesn't match the stored one should be rejected")
+		require.Empty(t, datastore.unsubscribed, "A forged token should not unsubscribe")
+	})
+
+	t.Run("subscription token", func(t *testing.T) {
+		datastore := newFakeDatastore(models.EmailSubscription{Email: "a@example.com", SubscriptionToken: token, UnsubscribeToken: "other"})
+		service := New(nil, datastore, logger.NewNop(), nil)
+		err := service.UnsubscribeWithToken(ctx, token)
+		require.ErrorIs(t, err, models.ErrInvalidSubscriptionToken, "The verification token should not unsubscribe")
+		require.Empty(t, datastore.unsubscribed, "The verification token should not unsubscribe")
+	})
+
+	t.Run("missing stored token", func(t *testing.T) {
+		datastore := newFakeDatastore(models.EmailSubscription{Email: "a@example.com"})
+		service := New(nil, datastore, logger.NewNop(), nil)
+		err := service.UnsubscribeWithToken(ctx, base64.StdEncoding.EncodeToString([]byte("a@example.com"))+":")
+		require.ErrorIs(t, err, models.ErrInvalidSubscriptionToken, "A subscription without an unsubscribe token should reject every token")
+	})
+
+	t.Run("missing subscription", func(t *testing.T) {
+		service := New(nil, newFakeDatastore(), logger.NewNop(), nil)
+		err := service.UnsubscribeWithToken(ctx, token)
+		require.ErrorIs(t, err, models.ErrEmailSubscriptionNotFound, "A token for an unknown email should report the subscription as missing")
+	})
+
+	t.Run("matching token", func(t *testing.T) {
+		datastore := newFakeDatastore(models.EmailSubscription{Email: "a@example.com", UnsubscribeToken: token})
+		service := New(nil, datastore, logger.NewNop(), nil)
+		require.NoError(t, service.UnsubscribeWithToken(ctx, token), "The stored token should unsubscribe")
+		require.NoError(t, service.UnsubscribeWithToken(ctx, token), "Unsubscribing twice should succeed")
+		require.Equal(t, []string{"a@example.com"}, datastore.unsubscribed, "The subscription should be unsubscribed once")
+	})
+}
+
+func TestSendTemplatedEmail(t *testing.T) {
+	ctx := context.Background()
+	datastore := newFakeDatastore(
+		models.EmailSubscription{Email: "active@example.com"},
+		models.EmailSubscription{Email: "unsubscribed@example.com", Unsubscribed: true},
+		models.EmailSubscription{Email: "complained@example.com", HasComplaint: true},
+		models.EmailSubscription{Email: "bounced@example.com", HasBounce: true, BounceType: "Permanent"},
+		models.EmailSubscription{Email: "transient@example.com", HasBounce: true, BounceType: "MailboxFull"},
+	)
+	sender := &fakeSender{}
+	service := New(sender, datastore, logger.NewNop(), nil)
+	params := map[string]string{"verificationLink": "https://example.com/verify"}
+
+	toAddresses := []string{
+		"active@example.com",
+		"unsubscribed@example.com",
+		"complained@example.com",
+		"bounced@example.com",
+		"transient@example.com",
+		"unknown@example.com",
+	}
+	require.NoError(t, service.SendTemplatedEmail(ctx, "email-subscription-verification", params, "subject", "from@example.com", toAddresses, nil), "Sending should succeed")
+	require.Equal(t, [][]string{{"active@example.com", "transient@example.com"}}, sender.toAddresses, "Only reachable subscriptions should receive the email")
+
+	sender.toAddresses = nil
+	require.NoError(t, service.SendTemplatedEmail(ctx, "email-subscription-verification", params, "subject", "from@example.com", []string{"unsubscribed@example.com"}, nil), "Sending to no reachable recipient should succeed")
+	require.Empty(t, sender.toAddresses, "No email should be sent when every recipient is filtered out")
+}
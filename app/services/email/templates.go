@@ -22,5 +22,5 @@ func generateEmailBodyFromTemplate(templateName string, params map[string]string
 }
 
 var EmailTemplateCollection = map[string]string{
-	"email-subscription-verification": "Thank you for your interest in SaintSpace!\n\nPlease click on the link below to confirm your subscription. It helps us make sure you're human.\n\n {{.verificationLink}}\n\n",
+	"email-subscription-verification": "Thank you for your interest in SaintSpace!\n\nPlease click on the link below to confirm your subscription. It helps us make sure you're human.\n\n {{.verificationLink}}\n\n{{if .unsubscribeLink}}If you no longer wish to receive emails from us, you can unsubscribe here:\n\n {{.unsubscribeLink}}\n\n{{end}}",
 }
@@ -1,10 +1,14 @@
 package eventpub
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"score/app/correlation"
 	"score/app/models"
+
+	"github.com/google/uuid"
 )
 
 // EventPublisher is responsible for building & publishing events to be processed later by a worker
@@ -14,7 +18,7 @@ type EventPublisher struct {
 }
 
 type Notifier interface {
-	PublishPlatformEventMessage(message string) error
+	PublishPlatformEventMessage(ctx context.Context, message string) error
 }
 
 type Config interface {
@@ -29,7 +33,7 @@ func New(notifier Notifier, config Config) *EventPublisher {
 	}
 }
 
-func (s *EventPublisher) PublishEmailVerificationTask(email, token string) error {
+func (s *EventPublisher) PublishEmailVerificationTask(ctx context.Context, email, token, unsubscribeToken string) error {
 	escapedToken := url.QueryEscape(token)
 	linkTemplate := "https://%s/saintspace/universe/verify-email-subscription?token=%s"
 	link := fmt.Sprintf(linkTemplate, s.config.WebAppDomainName(), escapedToken)
@@ -42,23 +46,59 @@ func (s *EventPublisher) PublishEmailVerificationTask(email, token string) error
 			"verificationLink": link,
 		},
 	}
+	if unsubscribeToken != "" {
+		escapedUnsubscribeToken := url.QueryEscape(unsubscribeToken)
+		unsubscribeLinkTemplate := "https://%s/saintspace/universe/unsubscribe?token=%s"
+		oneClickLinkTemplate := "https://%s/v1/email-subscriptions/unsubscribe/one-click?token=%s"
+		emailSendTask.Parameters["unsubscribeLink"] = fmt.Sprintf(unsubscribeLinkTemplate, s.config.WebAppDomainName(), escapedUnsubscribeToken)
+		// RFC 8058 one-click unsubscribe headers
+		emailSendTask.Headers = map[string]string{
+			"List-Unsubscribe":      fmt.Sprintf("<%s>", fmt.Sprintf(oneClickLinkTemplate, s.config.WebAppDomainName(), escapedUnsubscribeToken)),
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}
+	}
 	emailSendTaskBytes, err := json.Marshal(emailSendTask)
 	if err != nil {
 		return fmt.Errorf("error while marshaling email send task details => %v", err.Error())
 	} else {
 		task := models.PlatformEvent{
+			EventId:       uuid.New().String(),
 			EventName:     "email-send-task",
-			CorrelationId: "",
+			CorrelationId: correlation.FromContext(ctx),
 			EventDetails:  string(emailSendTaskBytes),
 		}
 		taskBytes, err := json.Marshal(task)
 		if err != nil {
 			return fmt.Errorf("error while marshaling email send task => %v", err.Error())
 		} else {
-			if err := s.notifier.PublishPlatformEventMessage(string(taskBytes)); err != nil {
+			if err := s.notifier.PublishPlatformEventMessage(ctx, string(taskBytes)); err != nil {
 				return fmt.Errorf("error while publishing email send task => %v", err.Error())
 			}
 		}
 	}
 	return nil
 }
+
+func (s *EventPublisher) PublishAccountDeletedEvent(ctx context.Context, email string) error {
+	accountDeletedEvent := models.AccountDeletedPlatformEvent{
+		EmailAddress: email,
+	}
+	accountDeletedEventBytes, err := json.Marshal(accountDeletedEvent)
+	if err != nil {
+		return fmt.Errorf("error while marshaling account deleted event details => %v", err.Error())
+	}
+	event := models.PlatformEvent{
+		EventId:       uuid.New().String(),
+		EventName:     "account-deleted",
+		CorrelationId: correlation.FromContext(ctx),
+		EventDetails:  string(accountDeletedEventBytes),
+	}
+	eventBytes, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error while marshaling account deleted event => %v", err.Error())
+	}
+	if err := s.notifier.PublishPlatformEventMessage(ctx, string(eventBytes)); err != nil {
+		return fmt.Errorf("error while publishing account deleted event => %v", err.Error())
+	}
+	return nil
+}
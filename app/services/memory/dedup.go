@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"score/app/models"
+	"sync"
+	"time"
+)
+
+// eventClaimTimeout is how long a claim keeps other deliveries of the event from processing it
+const eventClaimTimeout = time.Minute
+
+// DeduplicationStore keeps claimed and processed event IDs in memory. It's meant for tests and local
+// runs where processed events don't need to survive a restart.
+type DeduplicationStore struct {
+	mutex sync.Mutex
+	// claims holds the expiry of the claims on events being processed
+	claims          map[string]time.Time
+	processedEvents map[string]struct{}
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+func NewDeduplicationStore() *DeduplicationStore {
+	return &DeduplicationStore{
+		claims:          map[string]time.Time{},
+		processedEvents: map[string]struct{}{},
+		now:             time.Now,
+	}
+}
+
+// ClaimEvent records that the event is being processed. It fails with models.ErrEventAlreadyProcessed
+// when the event was processed, and with models.ErrEventInProgress while another delivery holds an
+// unexpired claim on it.
+func (s *DeduplicationStore) ClaimEvent(ctx context.Context, eventId string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, processed := s.processedEvents[eventId]; processed {
+		return fmt.Errorf("%w: %v", models.ErrEventAlreadyProcessed, eventId)
+	}
+	now := s.now()
+	if expiresAt, claimed := s.claims[eventId]; claimed && expiresAt.After(now) {
+		return fmt.Errorf("%w: %v", models.ErrEventInProgress, eventId)
+	}
+	s.claims[eventId] = now.Add(eventClaimTimeout)
+	return nil
+}
+
+// ReleaseEvent removes the claim of an event that failed so that its redelivery is processed
+func (s *DeduplicationStore) ReleaseEvent(ctx context.Context, eventId string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.claims, eventId)
+	return nil
+}
+
+func (s *DeduplicationStore) AddProcessedEvent(ctx context.Context, eventId string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.claims, eventId)
+	s.processedEvents[eventId] = struct{}{}
+	return nil
+}
@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"context"
+	"score/app/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeduplicationStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("claimed events are in progress until the claim expires", func(t *testing.T) {
+		store := NewDeduplicationStore()
+		now := time.Now()
+		store.now = func() time.Time { return now }
+		require.NoError(t, store.ClaimEvent(ctx, "event-1"), "First claim should succeed")
+		require.ErrorIs(t, store.ClaimEvent(ctx, "event-1"), models.ErrEventInProgress, "Second claim should find the event in progress")
+		now = now.Add(eventClaimTimeout)
+		require.NoError(t, store.ClaimEvent(ctx, "event-1"), "Expired claims should be taken over")
+	})
+
+	t.Run("released events can be claimed again", func(t *testing.T) {
+		store := NewDeduplicationStore()
+		require.NoError(t, store.ClaimEvent(ctx, "event-1"), "First claim should succeed")
+		require.NoError(t, store.ReleaseEvent(ctx, "event-1"), "Releasing the claim should succeed")
+		require.NoError(t, store.ClaimEvent(ctx, "event-1"), "Released events should be claimable")
+	})
+
+	t.Run("processed events are not claimed again", func(t *testing.T) {
+		store := NewDeduplicationStore()
+		require.NoError(t, store.ClaimEvent(ctx, "event-1"), "First claim should succeed")
+		require.NoError(t, store.AddProcessedEvent(ctx, "event-1"), "Marking the event processed should succeed")
+		require.ErrorIs(t, store.ClaimEvent(ctx, "event-1"), models.ErrEventAlreadyProcessed, "Processed events should be reported as such")
+	})
+}
@@ -0,0 +1,30 @@
+//go:build unix
+
+package memory
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// lockFile takes a lock on the file at path, creating it if needed, and returns the function that
+// releases it. Exclusive locks wait for every other lock, shared locks only for exclusive ones.
+func lockFile(path string, exclusive bool) (func(), error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening lock file %s => %v", path, err.Error())
+	}
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	if err := syscall.Flock(int(file.Fd()), how); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error locking %s => %v", path, err.Error())
+	}
+	return func() {
+		syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}, nil
+}
@@ -0,0 +1,316 @@
+package memory
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"score/app/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// KeyValueStore keeps email subscriptions in memory with the same semantics as the DynamoDB
+// implementation: creating a subscription fails when it already exists, and updates fail when it's
+// missing. A store opened with NewFileKeyValueStore also persists the subscriptions to a JSON file
+// for local runs.
+type KeyValueStore struct {
+	mutex         sync.Mutex
+	subscriptions map[string]models.EmailSubscription
+	// path is the JSON file backing the store, empty for a purely in-memory store
+	path string
+}
+
+func NewKeyValueStore() *KeyValueStore {
+	return &KeyValueStore{
+		subscriptions: map[string]models.EmailSubscription{},
+	}
+}
+
+// NewFileKeyValueStore opens a store backed by the JSON file at path, which is created on the first
+// write if it doesn't exist. The file is re-read on every operation so that processes sharing it,
+// such as a local server and worker, see each other's writes. Operations hold a lock on the sidecar
+// file path+".lock" so that writes of different processes don't overwrite each other.
+func NewFileKeyValueStore(path string) (*KeyValueStore, error) {
+	s := &KeyValueStore{
+		subscriptions: map[string]models.EmailSubscription{},
+		path:          path,
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load replaces the subscriptions with the contents of the backing file
+func (s *KeyValueStore) load() error {
+	if s.path == "" {
+		return nil
+	}
+	contents, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		s.subscriptions = map[string]models.EmailSubscription{}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading key value store file %s => %v", s.path, err.Error())
+	}
+	subscriptions := map[string]models.EmailSubscription{}
+	if err := json.Unmarshal(contents, &subscriptions); err != nil {
+		return fmt.Errorf("error parsing key value store file %s => %v", s.path, err.Error())
+	}
+	s.subscriptions = subscriptions
+	return nil
+}
+
+// save writes the subscriptions to the backing file, replacing it atomically so that a concurrent
+// reader never sees a partial file
+func (s *KeyValueStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	contents, err := json.MarshalIndent(s.subscriptions, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing key value store file %s => %v", s.path, err.Error())
+	}
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.Write(contents)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), s.path)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing key value store file %s => %v", s.path, err.Error())
+	}
+	return nil
+}
+
+// lock takes the lock on the backing file, if any, and returns the function that releases it
+func (s *KeyValueStore) lock(exclusive bool) (func(), error) {
+	if s.path == "" {
+		return func() {}, nil
+	}
+	return lockFile(s.path+".lock", exclusive)
+}
+
+// read runs fn on the latest subscriptions
+func (s *KeyValueStore) read(fn func(subscriptions map[string]models.EmailSubscription)) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	unlock, err := s.lock(false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	fn(s.subscriptions)
+	return nil
+}
+
+// write runs fn on the latest subscriptions and persists its changes unless it fails. The file stays
+// locked from the load to the save so that no other write happens in between.
+func (s *KeyValueStore) write(fn func(subscriptions map[string]models.EmailSubscription) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	unlock, err := s.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	if err := fn(s.subscriptions); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// update applies fn to the subscription of email, failing when it's missing
+func (s *KeyValueStore) update(email string, fn func(subscription *models.EmailSubscription)) error {
+	return s.write(func(subscriptions map[string]models.EmailSubscription) error {
+		subscription, ok := subscriptions[email]
+		if !ok {
+			return fmt.Errorf("%w: %v", models.ErrEmailSubscriptionNotFound, email)
+		}
+		fn(&subscription)
+		subscriptions[email] = subscription
+		return nil
+	})
+}
+
+func (s *KeyValueStore) GetEmailSubscription(ctx context.Context, email string) (*models.EmailSubscription, error) {
+	var emailSubscription *models.EmailSubscription
+	err := s.read(func(subscriptions map[string]models.EmailSubscription) {
+		if subscription, ok := subscriptions[email]; ok {
+			emailSubscription = &subscription
+		}
+	})
+	return emailSubscription, err
+}
+
+func (s *KeyValueStore) CreateEmailSubscriptionItem(
+	ctx context.Context,
+	email string,
+	subscriptionToken string,
+	unsubscribeToken string,
+	tokenIssuedUnix int64,
+	tokenExpiryUnix int64,
+	isVerified bool,
+) error {
+	return s.write(func(subscriptions map[string]models.EmailSubscription) error {
+		if _, ok := subscriptions[email]; ok {
+			return fmt.Errorf("%w: %v", models.ErrEmailSubscriptionExists, email)
+		}
+		subscriptions[email] = models.EmailSubscription{
+			Email:                       email,
+			CreationDate:                time.Now().Unix(),
+			SubscriptionToken:           subscriptionToken,
+			SubscriptionTokenIssuedUnix: tokenIssuedUnix,
+			SubscriptionTokenExpiryUnix: tokenExpiryUnix,
+			UnsubscribeToken:            unsubscribeToken,
+			Verified:                    isVerified,
+		}
+		return nil
+	})
+}
+
+func (s *KeyValueStore) VerifyEmailSubscription(ctx context.Context, email string) error {
+	return s.update(email, func(subscription *models.EmailSubscription) {
+		subscription.Verified = true
+		subscription.SubscriptionToken = ""
+		subscription.SubscriptionTokenIssuedUnix = 0
+		subscription.SubscriptionTokenExpiryUnix = 0
+	})
+}
+
+// RotateSubscriptionToken replaces the subscription token, failing with
+// models.ErrEmailSubscriptionChanged when the stored token wasn't issued at previousTokenIssuedUnix
+func (s *KeyValueStore) RotateSubscriptionToken(
+	ctx context.Context,
+	email string,
+	subscriptionToken string,
+	previousTokenIssuedUnix int64,
+	tokenIssuedUnix int64,
+	tokenExpiryUnix int64,
+) error {
+	return s.write(func(subscriptions map[string]models.EmailSubscription) error {
+		subscription, ok := subscriptions[email]
+		if !ok {
+			return fmt.Errorf("%w: %v", models.ErrEmailSubscriptionNotFound, email)
+		}
+		if subscription.SubscriptionTokenIssuedUnix != previousTokenIssuedUnix {
+			return fmt.Errorf("%w: %v", models.ErrEmailSubscriptionChanged, email)
+		}
+		subscription.SubscriptionToken = subscriptionToken
+		subscription.SubscriptionTokenIssuedUnix = tokenIssuedUnix
+		subscription.SubscriptionTokenExpiryUnix = tokenExpiryUnix
+		subscriptions[email] = subscription
+		return nil
+	})
+}
+
+// SetUnsubscribeToken stores the unsubscribe token, failing with models.ErrEmailSubscriptionChanged
+// when the subscription already has one
+func (s *KeyValueStore) SetUnsubscribeToken(ctx context.Context, email string, unsubscribeToken string) error {
+	return s.write(func(subscriptions map[string]models.EmailSubscription) error {
+		subscription, ok := subscriptions[email]
+		if !ok {
+			return fmt.Errorf("%w: %v", models.ErrEmailSubscriptionNotFound, email)
+		}
+		if subscription.UnsubscribeToken != "" {
+			return fmt.Errorf("%w: %v", models.ErrEmailSubscriptionChanged, email)
+		}
+		subscription.UnsubscribeToken = unsubscribeToken
+		subscriptions[email] = subscription
+		return nil
+	})
+}
+
+func (s *KeyValueStore) UnsubscribeEmailSubscription(ctx context.Context, email string, unsubscribeDateUnix int64) error {
+	return s.update(email, func(subscription *models.EmailSubscription) {
+		subscription.Unsubscribed = true
+		subscription.UnsubscribeDateUnix = unsubscribeDateUnix
+	})
+}
+
+func (s *KeyValueStore) AddComplaintToEmailSubscription(ctx context.Context, email, complaintDetails string, complaintDateUnix int64) error {
+	return s.update(email, func(subscription *models.EmailSubscription) {
+		subscription.HasComplaint = true
+		subscription.ComplaintDateUnix = complaintDateUnix
+		subscription.ComplaintDetails = complaintDetails
+	})
+}
+
+func (s *KeyValueStore) AddBounceToEmailSubscription(
+	ctx context.Context,
+	email,
+	bounceType string,
+	bounceDetails string,
+	bounceDateUnix int64,
+) error {
+	return s.update(email, func(subscription *models.EmailSubscription) {
+		subscription.HasBounce = true
+		subscription.BounceDateUnix = bounceDateUnix
+		subscription.BounceType = bounceType
+		subscription.BounceDetails = bounceDetails
+	})
+}
+
+func (s *KeyValueStore) DeleteEmailSubscriptionItem(ctx context.Context, email string) error {
+	return s.write(func(subscriptions map[string]models.EmailSubscription) error {
+		delete(subscriptions, email)
+		return nil
+	})
+}
+
+// ListEmailSubscriptions lists the subscriptions in email order. The cursor encodes the last email
+// of the previous page.
+func (s *KeyValueStore) ListEmailSubscriptions(ctx context.Context, filter models.EmailSubscriptionFilter, cursor string, limit int) (*models.EmailSubscriptionPage, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("%w: %d", models.ErrInvalidPageLimit, limit)
+	}
+	after := ""
+	if cursor != "" {
+		decodedCursor, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil || len(decodedCursor) == 0 {
+			return nil, fmt.Errorf("%w: %v", models.ErrInvalidCursor, cursor)
+		}
+		after = string(decodedCursor)
+	}
+	page := &models.EmailSubscriptionPage{Subscriptions: []models.EmailSubscription{}}
+	err := s.read(func(subscriptions map[string]models.EmailSubscription) {
+		emails := make([]string, 0, len(subscriptions))
+		for email := range subscriptions {
+			emails = append(emails, email)
+		}
+		sort.Strings(emails)
+		for i := sort.SearchStrings(emails, after); i < len(emails); i++ {
+			if emails[i] == after {
+				continue
+			}
+			if len(page.Subscriptions) == limit {
+				page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(emails[i-1]))
+				return
+			}
+			subscription := subscriptions[emails[i]]
+			if filter.Matches(&subscription) {
+				page.Subscriptions = append(page.Subscriptions, subscription)
+			}
+		}
+	})
+	return page, err
+}
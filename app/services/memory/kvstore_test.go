@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"score/app/models"
+	"score/app/services/datastore"
+	"score/app/services/datastore/kvtest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyValueStore(t *testing.T) {
+	kvtest.Run(t, func(t *testing.T) datastore.KeyValueStore {
+		return NewKeyValueStore()
+	})
+}
+
+func TestFileKeyValueStore(t *testing.T) {
+	kvtest.Run(t, func(t *testing.T) datastore.KeyValueStore {
+		store, err := NewFileKeyValueStore(filepath.Join(t.TempDir(), "subscriptions.json"))
+		require.NoError(t, err, "Opening a missing file should succeed")
+		return store
+	})
+}
+
+func TestFileKeyValueStoreSharesWrites(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "subscriptions.json")
+	writer, err := NewFileKeyValueStore(path)
+	require.NoError(t, err, "Opening the writer should succeed")
+	reader, err := NewFileKeyValueStore(path)
+	require.NoError(t, err, "Opening the reader should succeed")
+
+	require.NoError(t, writer.CreateEmailSubscriptionItem(ctx, "a@example.com", "token", "unsubscribe", 100, 200, false), "Creating a subscription should succeed")
+	subscription, err := reader.GetEmailSubscription(ctx, "a@example.com")
+	require.NoError(t, err, "Reading the file should succeed")
+	require.NotNil(t, subscription, "Writes should be visible to other stores sharing the file")
+}
+
+func TestFileKeyValueStoreConcurrentWriters(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "subscriptions.json")
+	// Each store stands for a process sharing the file, so only the file lock orders their writes
+	stores := make([]*KeyValueStore, 4)
+	for i := range stores {
+		store, err := NewFileKeyValueStore(path)
+		require.NoError(t, err, "Opening the store should succeed")
+		stores[i] = store
+	}
+	const writesPerStore = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, len(stores)*writesPerStore)
+	for i, store := range stores {
+		wg.Add(1)
+		go func(i int, store *KeyValueStore) {
+			defer wg.Done()
+			for j := 0; j < writesPerStore; j++ {
+				errs <- store.CreateEmailSubscriptionItem(ctx, fmt.Sprintf("%d-%d@example.com", i, j), "token", "unsubscribe", 100, 200, false)
+			}
+		}(i, store)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err, "Concurrent creates should succeed")
+	}
+	page, err := stores[0].ListEmailSubscriptions(ctx, models.EmailSubscriptionFilter{}, "", 1000)
+	require.NoError(t, err, "Listing subscriptions should succeed")
+	require.Len(t, page.Subscriptions, len(stores)*writesPerStore, "No write should be lost")
+}
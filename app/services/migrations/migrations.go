@@ -0,0 +1,258 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migrations are SQL files named <version>_<name>.up.sql and <version>_<name>.down.sql. They must
+// stick to SQL that both MySQL and SQLite understand, and timestamps are stored as BIGINT unix seconds.
+//
+//go:embed sql/*.sql
+var migrationFiles embed.FS
+
+var migrationFileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// schemaMigrationsTable records the applied migrations
+const schemaMigrationsTable = "schema_migrations"
+
+// BaselineVersion is the last migration describing the schema that databases created before
+// migrations were introduced already have
+const BaselineVersion = 1
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      []string
+	Down    []string
+}
+
+// MigrationStatus tells whether a migration was applied, and when
+type MigrationStatus struct {
+	Version       int64
+	Name          string
+	Applied       bool
+	AppliedAtUnix int64
+}
+
+// Migrator applies the embedded migrations to a database
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func New(db *sql.DB) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, fmt.Errorf("error loading migrations: %v", err)
+	}
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies every pending migration in version order and returns the applied migrations
+func (s *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	appliedVersions, err := s.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := []Migration{}
+	for _, migration := range s.migrations {
+		if _, ok := appliedVersions[migration.Version]; ok {
+			continue
+		}
+		err := s.run(ctx, migration.Up, "INSERT INTO "+schemaMigrationsTable+" (version, name, applied_at) VALUES (?, ?, ?)",
+			migration.Version, migration.Name, time.Now().Unix())
+		if err != nil {
+			return applied, fmt.Errorf("error applying migration %d_%s: %v", migration.Version, migration.Name, err)
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// Baseline records the migrations up to BaselineVersion as applied without running them, for databases
+// whose schema predates migrations. It returns the migrations it recorded.
+func (s *Migrator) Baseline(ctx context.Context) ([]Migration, error) {
+	appliedVersions, err := s.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recorded := []Migration{}
+	for _, migration := range s.migrations {
+		if migration.Version > BaselineVersion {
+			break
+		}
+		if _, ok := appliedVersions[migration.Version]; ok {
+			continue
+		}
+		err := s.run(ctx, nil, "INSERT INTO "+schemaMigrationsTable+" (version, name, applied_at) VALUES (?, ?, ?)",
+			migration.Version, migration.Name, time.Now().Unix())
+		if err != nil {
+			return recorded, fmt.Errorf("error recording migration %d_%s: %v", migration.Version, migration.Name, err)
+		}
+		recorded = append(recorded, migration)
+	}
+	return recorded, nil
+}
+
+// Down rolls back the most recently applied migration. It returns nil when no migration is applied.
+func (s *Migrator) Down(ctx context.Context) (*Migration, error) {
+	appliedVersions, err := s.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(s.migrations) - 1; i >= 0; i-- {
+		migration := s.migrations[i]
+		if _, ok := appliedVersions[migration.Version]; !ok {
+			continue
+		}
+		err := s.run(ctx, migration.Down, "DELETE FROM "+schemaMigrationsTable+" WHERE version = ?", migration.Version)
+		if err != nil {
+			return nil, fmt.Errorf("error rolling back migration %d_%s: %v", migration.Version, migration.Name, err)
+		}
+		return &migration, nil
+	}
+	return nil, nil
+}
+
+// Status lists every migration in version order
+func (s *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	appliedVersions, err := s.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := []MigrationStatus{}
+	for _, migration := range s.migrations {
+		appliedAt, applied := appliedVersions[migration.Version]
+		statuses = append(statuses, MigrationStatus{
+			Version:       migration.Version,
+			Name:          migration.Name,
+			Applied:       applied,
+			AppliedAtUnix: appliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// appliedVersions creates the schema migrations table if needed and returns the applied versions
+// with the time they were applied
+func (s *Migrator) appliedVersions(ctx context.Context) (map[int64]int64, error) {
+	_, err := s.db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+schemaMigrationsTable+` (
+		version BIGINT NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		applied_at BIGINT NOT NULL,
+		PRIMARY KEY (version)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("error creating %s table: %v", schemaMigrationsTable, err)
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT version, applied_at FROM "+schemaMigrationsTable)
+	if err != nil {
+		return nil, fmt.Errorf("error reading applied migrations: %v", err)
+	}
+	defer rows.Close()
+	appliedVersions := map[int64]int64{}
+	for rows.Next() {
+		var version, appliedAt int64
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("error reading applied migrations: %v", err)
+		}
+		appliedVersions[version] = appliedAt
+	}
+	return appliedVersions, rows.Err()
+}
+
+// run executes the statements of a migration and records it in a transaction. MySQL commits DDL
+// statements implicitly, so a failed migration there may need to be cleaned up by hand.
+func (s *Migrator) run(ctx context.Context, statements []string, recordStatement string, recordArgs ...interface{}) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, recordStatement, recordArgs...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func loadMigrations(files fs.FS) ([]Migration, error) {
+	fileNames, err := fs.Glob(files, "sql/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	migrationsByVersion := map[int64]*Migration{}
+	for _, fileName := range fileNames {
+		matches := migrationFileNamePattern.FindStringSubmatch(strings.TrimPrefix(fileName, "sql/"))
+		if matches == nil {
+			return nil, fmt.Errorf("migration file %s isn't named <version>_<name>.(up|down).sql", fileName)
+		}
+		version, _ := strconv.ParseInt(matches[1], 10, 64)
+		contents, err := fs.ReadFile(files, fileName)
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := migrationsByVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			migrationsByVersion[version] = migration
+		} else if migration.Name != matches[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, migration.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			migration.Up = splitStatements(string(contents))
+		} else {
+			migration.Down = splitStatements(string(contents))
+		}
+	}
+	migrations := []Migration{}
+	for _, migration := range migrationsByVersion {
+		if migration.Up == nil || migration.Down == nil {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// splitStatements splits a migration file into statements on semicolons ending a line, since the
+// MySQL driver only runs one statement per call by default. Lines starting with -- are comments.
+func splitStatements(contents string) []string {
+	statements := []string{}
+	current := []string{}
+	for _, line := range strings.Split(contents, "\n") {
+		trimmedLine := strings.TrimSpace(line)
+		if trimmedLine == "" || strings.HasPrefix(trimmedLine, "--") {
+			continue
+		}
+		current = append(current, line)
+		if strings.HasSuffix(trimmedLine, ";") {
+			statement := strings.TrimSuffix(strings.TrimSpace(strings.Join(current, "\n")), ";")
+			statements = append(statements, statement)
+			current = []string{}
+		}
+	}
+	if statement := strings.TrimSpace(strings.Join(current, "\n")); statement != "" {
+		statements = append(statements, statement)
+	}
+	return statements
+}
@@ -0,0 +1,79 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles)
+	require.NoError(t, err, "Embedded migrations should load")
+	require.NotEmpty(t, migrations, "There should be embedded migrations")
+	for i, migration := range migrations {
+		require.NotEmpty(t, migration.Up, "Migration %d should have up statements", migration.Version)
+		require.NotEmpty(t, migration.Down, "Migration %d should have down statements", migration.Version)
+		if i > 0 {
+			require.Greater(t, migration.Version, migrations[i-1].Version, "Migrations should be sorted by version")
+		}
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("statements are split", func(t *testing.T) {
+		migrations, err := loadMigrations(fstest.MapFS{
+			"sql/0002_second.up.sql":   {Data: []byte("-- comment\nCREATE TABLE b (\n  id BIGINT\n);\nCREATE INDEX b_id ON b (id);\n")},
+			"sql/0002_second.down.sql": {Data: []byte("DROP TABLE b")},
+			"sql/0001_first.up.sql":    {Data: []byte("CREATE TABLE a (id BIGINT);")},
+			"sql/0001_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+		})
+		require.NoError(t, err, "Migrations should load")
+		require.Equal(t, []Migration{
+			{Version: 1, Name: "first", Up: []string{"CREATE TABLE a (id BIGINT)"}, Down: []string{"DROP TABLE a"}},
+			{Version: 2, Name: "second", Up: []string{"CREATE TABLE b (\n  id BIGINT\n)", "CREATE INDEX b_id ON b (id)"}, Down: []string{"DROP TABLE b"}},
+		}, migrations, "Migrations should be sorted with one entry per statement")
+	})
+
+	t.Run("missing down migration", func(t *testing.T) {
+		_, err := loadMigrations(fstest.MapFS{
+			"sql/0001_first.up.sql": {Data: []byte("CREATE TABLE a (id BIGINT);")},
+		})
+		require.Error(t, err, "Migrations without a down file should be rejected")
+	})
+
+	t.Run("badly named file", func(t *testing.T) {
+		_, err := loadMigrations(fstest.MapFS{
+			"sql/first.sql": {Data: []byte("CREATE TABLE a (id BIGINT);")},
+		})
+		require.Error(t, err, "Files not following the naming scheme should be rejected")
+	})
+}
+
+func TestBaseline(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "score.db"))
+	require.NoError(t, err, "Opening the database should succeed")
+	defer db.Close()
+	// The users table of a database created before migrations were introduced
+	_, err = db.ExecContext(ctx, "CREATE TABLE users (id VARCHAR(36) NOT NULL, email VARCHAR(255) NOT NULL, cognito_user_id VARCHAR(255) NOT NULL, PRIMARY KEY (id))")
+	require.NoError(t, err, "Creating the existing users table should succeed")
+	migrator, err := New(db)
+	require.NoError(t, err, "Creating the migrator should succeed")
+
+	recorded, err := migrator.Baseline(ctx)
+	require.NoError(t, err, "Baseline should succeed")
+	require.Len(t, recorded, BaselineVersion, "Baseline should record the migrations up to the baseline version")
+	recorded, err = migrator.Baseline(ctx)
+	require.NoError(t, err, "Repeating the baseline should succeed")
+	require.Empty(t, recorded, "Repeating the baseline should not record anything")
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err, "Applying the later migrations should succeed")
+	require.NotEmpty(t, applied, "The migrations after the baseline should be applied")
+	require.Greater(t, applied[0].Version, int64(BaselineVersion), "Baseline migrations should not run again")
+}
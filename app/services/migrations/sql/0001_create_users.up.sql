@@ -0,0 +1,10 @@
+CREATE TABLE users (
+    id VARCHAR(36) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    cognito_user_id VARCHAR(255) NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX users_email ON users (email);
+
+CREATE UNIQUE INDEX users_cognito_user_id ON users (cognito_user_id);
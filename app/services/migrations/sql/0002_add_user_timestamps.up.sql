@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN created_at BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE users ADD COLUMN updated_at BIGINT NOT NULL DEFAULT 0;
+
+-- 0 unless the user was soft deleted
+ALTER TABLE users ADD COLUMN deleted_at BIGINT NOT NULL DEFAULT 0;
@@ -1,46 +1,102 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"score/app/services/sqlusers"
+	"sync"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/google/uuid"
 )
 
+// MySQL owns a long lived connection pool to the relational database
 type MySQL struct {
+	*sqlusers.Users
 	config Config
+	// mutex is held for reading while a query uses the pool, so that the pool isn't closed under it
+	mutex sync.RWMutex
+	db    *sql.DB
 }
 
-func New(config Config) *MySQL {
-	return &MySQL{
+// New opens the connection pool. Connections are established lazily, use Ping to check that the
+// database is reachable.
+func New(config Config) (*MySQL, error) {
+	db, err := openPool(config, config.RelationalDatabaseConnectionString())
+	if err != nil {
+		return nil, err
+	}
+	s := &MySQL{
 		config: config,
+		db:     db,
 	}
+	s.Users = sqlusers.New(s, isDuplicateEntry)
+	return s, nil
 }
 
 type Config interface {
 	RelationalDatabaseConnectionString() string
+	MySQLMaxOpenConnections() int
+	MySQLMaxIdleConnections() int
+	MySQLConnectionMaxLifetime() time.Duration
+	MySQLConnectionMaxIdleTime() time.Duration
 }
 
-func (s *MySQL) CreateUser(email, cognitoUserName string) error {
-	db, err := sql.Open("mysql", s.config.RelationalDatabaseConnectionString())
+func openPool(config Config, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("mysql", dsn)
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %v", err)
+		return nil, fmt.Errorf("failed to open database connection pool: %v", err)
 	}
-	defer db.Close()
+	db.SetMaxOpenConns(config.MySQLMaxOpenConnections())
+	db.SetMaxIdleConns(config.MySQLMaxIdleConnections())
+	db.SetConnMaxLifetime(config.MySQLConnectionMaxLifetime())
+	db.SetConnMaxIdleTime(config.MySQLConnectionMaxIdleTime())
+	return db, nil
+}
 
-	stmt, err := db.Prepare("INSERT INTO users (id, email, cognito_user_id) VALUES (?, ?, ?)")
+// reconnectPingTimeout bounds the check that the database accepts the new DSN
+const reconnectPingTimeout = 10 * time.Second
+
+// Reconnect replaces the connection pool with one using the new DSN, e.g. after the database
+// credentials were rotated. The previous pool is kept when the database can't be reached with the
+// new DSN. The swap waits for queries running on the previous pool to finish.
+func (s *MySQL) Reconnect(dsn string) error {
+	db, err := openPool(s.config, dsn)
 	if err != nil {
-		return fmt.Errorf("failed to prepare SQL statement: %v", err)
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), reconnectPingTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to connect with the new DSN: %w", err)
 	}
-	defer stmt.Close()
+	s.mutex.Lock()
+	previous := s.db
+	s.db = db
+	s.mutex.Unlock()
+	return previous.Close()
+}
 
-	id := uuid.New().String()
+// Ping verifies that the database is reachable
+func (s *MySQL) Ping(ctx context.Context) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.db.PingContext(ctx)
+}
 
-	_, err = stmt.Exec(id, email, cognitoUserName)
-	if err != nil {
-		return fmt.Errorf("failed to execute SQL statement: %v", err)
-	}
+// DB returns the current connection pool for administrative tasks such as migrations. It must not
+// be held on to since a DSN rotation replaces the pool.
+func (s *MySQL) DB() *sql.DB {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.db
+}
 
-	return nil
+// Close closes the connection pool, waiting for running queries to finish
+func (s *MySQL) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.db.Close()
 }
@@ -0,0 +1,28 @@
+package mysql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	dsn string
+}
+
+func (c testConfig) RelationalDatabaseConnectionString() string { return c.dsn }
+func (c testConfig) MySQLMaxOpenConnections() int               { return 1 }
+func (c testConfig) MySQLMaxIdleConnections() int               { return 1 }
+func (c testConfig) MySQLConnectionMaxLifetime() time.Duration  { return time.Minute }
+func (c testConfig) MySQLConnectionMaxIdleTime() time.Duration  { return time.Minute }
+
+func TestReconnectKeepsPoolWhenUnreachable(t *testing.T) {
+	db, err := New(testConfig{dsn: "user:password@tcp(127.0.0.1:1)/score"})
+	require.NoError(t, err, "Opening the pool should not connect")
+	defer db.Close()
+	previous := db.DB()
+	err = db.Reconnect("user:rotated@tcp(127.0.0.1:1)/score")
+	require.Error(t, err, "Reconnecting to an unreachable database should fail")
+	require.Same(t, previous, db.DB(), "The previous pool should be kept")
+}
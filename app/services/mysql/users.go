@@ -0,0 +1,32 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// mysqlDuplicateEntryErrorNumber is the MySQL error raised when a unique index is violated
+const mysqlDuplicateEntryErrorNumber = 1062
+
+// ExecContext runs a statement on the current pool, so that the shared user repository survives a reconnect
+func (s *MySQL) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.db.ExecContext(ctx, query, args...)
+}
+
+// QueryRowContext runs a query on the current pool. The row may be scanned after a reconnect since
+// closing a pool waits for running queries to finish.
+func (s *MySQL) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.db.QueryRowContext(ctx, query, args...)
+}
+
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysqldriver.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntryErrorNumber
+}
@@ -0,0 +1,15 @@
+package mysql
+
+import (
+	"errors"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsDuplicateEntry(t *testing.T) {
+	require.True(t, isDuplicateEntry(&mysqldriver.MySQLError{Number: mysqlDuplicateEntryErrorNumber, Message: "Duplicate entry"}), "duplicate entry errors should be recognized")
+	require.False(t, isDuplicateEntry(&mysqldriver.MySQLError{Number: 1045, Message: "Access denied"}), "other MySQL errors should not be recognized")
+	require.False(t, isDuplicateEntry(errors.New("connection refused")), "non MySQL errors should not be recognized")
+}
@@ -0,0 +1,50 @@
+package relational
+
+import (
+	"context"
+	"database/sql"
+	"score/app/config"
+	"score/app/services/datastore"
+	"score/app/services/mysql"
+	"score/app/services/sqlite"
+	"strings"
+)
+
+// Database is a relational database backend along with the lifecycle operations the runners need
+type Database interface {
+	datastore.RelationalDB
+	Reconnect(dsn string) error
+	Ping(ctx context.Context) error
+	DB() *sql.DB
+	Close() error
+}
+
+type Config interface {
+	mysql.Config
+}
+
+type Logger interface {
+	InfoWithContext(message string, keysAndValues ...interface{})
+	ErrorWithContext(message string, keysAndValues ...interface{})
+}
+
+// New opens the backend selected by the scheme of the DSN: SQLite for DSNs prefixed with
+// config.SQLiteDsnScheme, and MySQL otherwise
+func New(ctx context.Context, conf Config) (Database, error) {
+	dsn := conf.RelationalDatabaseConnectionString()
+	if strings.HasPrefix(dsn, config.SQLiteDsnScheme) {
+		return sqlite.New(ctx, dsn)
+	}
+	return mysql.New(conf)
+}
+
+// ReconnectOnDsnChange returns a config subscriber that reconnects the database when a refresh rotates the DSN
+func ReconnectOnDsnChange(db Database, logger Logger) func(dsn string) {
+	return func(dsn string) {
+		if err := db.Reconnect(dsn); err != nil {
+			logger.ErrorWithContext("error reconnecting to the database with the rotated DSN", "error", err.Error())
+			return
+		}
+		logger.InfoWithContext("reconnected to the database with the rotated DSN")
+	}
+}
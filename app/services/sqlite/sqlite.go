@@ -0,0 +1,96 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"score/app/config"
+	"score/app/services/migrations"
+	"score/app/services/sqlusers"
+	"strings"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLite is a relational database backed by an SQLite file or memory, which lets the app run
+// locally and in tests without a database server
+type SQLite struct {
+	*sqlusers.Users
+	db *sql.DB
+}
+
+// connectionPragmas are applied to every connection. The busy timeout makes a write wait for the
+// lock held by another process sharing the file, e.g. the server and the worker, rather than fail
+// right away, and WAL mode lets reads proceed during that write.
+var connectionPragmas = []string{
+	"busy_timeout(5000)",
+	"journal_mode(wal)",
+}
+
+// New opens the database of a DSN prefixed with config.SQLiteDsnScheme and applies the pending
+// migrations, since there is no separate migrate step for local databases.
+func New(ctx context.Context, dsn string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", withPragmas(strings.TrimPrefix(dsn, config.SQLiteDsnScheme)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	// A single connection that's never recycled serializes writes, which SQLite requires, and keeps
+	// an in-memory database alive for as long as the pool is open
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error migrating database: %v", err)
+	}
+	return &SQLite{
+		Users: sqlusers.New(db, isDuplicateEntry),
+		db:    db,
+	}, nil
+}
+
+// Reconnect fails since an in-memory database would lose its contents, so a DSN change needs a restart
+func (s *SQLite) Reconnect(dsn string) error {
+	return errors.New("SQLite databases can't be reconnected, restart to use the new DSN")
+}
+
+// Ping verifies that the database is reachable
+func (s *SQLite) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// DB returns the connection pool for administrative tasks such as migrations
+func (s *SQLite) DB() *sql.DB {
+	return s.db
+}
+
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+// withPragmas appends connectionPragmas to the query parameters of the DSN
+func withPragmas(dsn string) string {
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	for _, pragma := range connectionPragmas {
+		dsn += separator + "_pragma=" + pragma
+		separator = "&"
+	}
+	return dsn
+}
+
+func isDuplicateEntry(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"score/app/models"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsers(t *testing.T) {
+	ctx := context.Background()
+	db, err := New(ctx, "sqlite://:memory:")
+	require.NoError(t, err, "Opening an in-memory database should apply the migrations")
+	defer db.Close()
+
+	require.NoError(t, db.CreateUser(ctx, "a@example.com", "cognito-a"), "Creating a user should succeed")
+	require.ErrorIs(t, db.CreateUser(ctx, "a@example.com", "cognito-b"), models.ErrUserAlreadyExists, "Emails should be unique")
+	require.ErrorIs(t, db.CreateUser(ctx, "b@example.com", "cognito-a"), models.ErrUserAlreadyExists, "Cognito user names should be unique")
+
+	user, err := db.GetUserByCognitoUserName(ctx, "cognito-a")
+	require.NoError(t, err, "The user should be found by Cognito user name")
+	require.Equal(t, "a@example.com", user.Email, "The user should have the email it was created with")
+	_, err = db.GetUserByEmail(ctx, "a@example.com")
+	require.NoError(t, err, "The user should be found by email")
+
+	require.NoError(t, db.CreateUser(ctx, "b@example.com", "cognito-b"), "Creating a second user should succeed")
+	require.ErrorIs(t, db.UpdateUserEmail(ctx, user.Id, "b@example.com"), models.ErrUserAlreadyExists, "Updating to a taken email should fail")
+	require.NoError(t, db.UpdateUserEmail(ctx, user.Id, "c@example.com"), "Updating the email should succeed")
+	require.NoError(t, db.UpdateUserEmail(ctx, user.Id, "c@example.com"), "Updating to the same email should succeed")
+	user, err = db.GetUserById(ctx, user.Id)
+	require.NoError(t, err, "The user should be found by id")
+	require.Equal(t, "c@example.com", user.Email, "The email should be updated")
+
+	require.NoError(t, db.SoftDeleteUser(ctx, user.Id), "Soft deleting the user should succeed")
+	_, err = db.GetUserById(ctx, user.Id)
+	require.ErrorIs(t, err, models.ErrUserNotFound, "Soft deleted users should be hidden")
+	require.ErrorIs(t, db.SoftDeleteUser(ctx, user.Id), models.ErrUserNotFound, "Soft deleting twice should fail")
+	require.NoError(t, db.DeleteUser(ctx, user.Id), "Hard deleting a soft deleted user should succeed")
+	require.ErrorIs(t, db.DeleteUser(ctx, user.Id), models.ErrUserNotFound, "Deleting a missing user should fail")
+	require.ErrorIs(t, db.UpdateUserEmail(ctx, "missing", "d@example.com"), models.ErrUserNotFound, "Updating a missing user should fail")
+}
+
+func TestCreateUserAfterSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	db, err := New(ctx, "sqlite://:memory:")
+	require.NoError(t, err, "Opening an in-memory database should apply the migrations")
+	defer db.Close()
+
+	require.NoError(t, db.CreateUser(ctx, "a@example.com", "cognito-a"), "Creating a user should succeed")
+	deleted, err := db.GetUserByEmail(ctx, "a@example.com")
+	require.NoError(t, err, "The user should be found by email")
+	require.NoError(t, db.SoftDeleteUser(ctx, deleted.Id), "Soft deleting the user should succeed")
+
+	require.NoError(t, db.CreateUser(ctx, "a@example.com", "cognito-a"), "The email and Cognito user name of a soft deleted user should be reusable")
+	user, err := db.GetUserByEmail(ctx, "a@example.com")
+	require.NoError(t, err, "The new user should be found by email")
+	require.NotEqual(t, deleted.Id, user.Id, "The new user should not be the soft deleted one")
+	user, err = db.GetUserByCognitoUserName(ctx, "cognito-a")
+	require.NoError(t, err, "The new user should be found by Cognito user name")
+	require.NotEqual(t, deleted.Id, user.Id, "The new user should not be the soft deleted one")
+
+	require.NoError(t, db.SoftDeleteUser(ctx, user.Id), "Soft deleting the new user should succeed")
+	require.NoError(t, db.CreateUser(ctx, "a@example.com", "cognito-a"), "Soft deleting the same email twice should keep it reusable")
+	require.NoError(t, db.DeleteUser(ctx, deleted.Id), "Hard deleting a soft deleted user should still find it by id")
+}
+
+func TestConnectionPragmas(t *testing.T) {
+	ctx := context.Background()
+	db, err := New(ctx, "sqlite://"+filepath.Join(t.TempDir(), "score.db"))
+	require.NoError(t, err, "Opening the database should succeed")
+	defer db.Close()
+
+	var journalMode string
+	require.NoError(t, db.DB().QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&journalMode), "Reading the journal mode should succeed")
+	require.Equal(t, "wal", journalMode, "File databases should use WAL mode")
+	var busyTimeout int
+	require.NoError(t, db.DB().QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&busyTimeout), "Reading the busy timeout should succeed")
+	require.Equal(t, 5000, busyTimeout, "Writes should wait for locks held by other processes")
+}
+
+func TestWithPragmas(t *testing.T) {
+	require.Equal(t, "score.db?_pragma=busy_timeout(5000)&_pragma=journal_mode(wal)", withPragmas("score.db"), "Pragmas should start the query")
+	require.Equal(t, "score.db?mode=ro&_pragma=busy_timeout(5000)&_pragma=journal_mode(wal)", withPragmas("score.db?mode=ro"), "Pragmas should extend an existing query")
+}
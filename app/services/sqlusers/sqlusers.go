@@ -0,0 +1,145 @@
+package sqlusers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"score/app/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Users implements the user repository with SQL that both MySQL and SQLite understand
+type Users struct {
+	conn             Conn
+	isDuplicateEntry func(err error) bool
+}
+
+// New builds the repository on conn. isDuplicateEntry recognizes the driver error raised when a
+// unique index is violated.
+func New(conn Conn, isDuplicateEntry func(err error) bool) *Users {
+	return &Users{
+		conn:             conn,
+		isDuplicateEntry: isDuplicateEntry,
+	}
+}
+
+type Conn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+const userColumns = "id, email, cognito_user_id, created_at, updated_at, deleted_at"
+
+// deletedUserPlaceholder prefixes the id stored in the unique columns of soft deleted users. It can't
+// be mistaken for an email since it has no @.
+const deletedUserPlaceholder = "deleted:"
+
+func (s *Users) CreateUser(ctx context.Context, email, cognitoUserName string) error {
+	id := uuid.New().String()
+	now := time.Now().Unix()
+
+	_, err := s.conn.ExecContext(ctx, "INSERT INTO users (id, email, cognito_user_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		id, email, cognitoUserName, now, now)
+	if err != nil {
+		return s.mapError(err, email)
+	}
+
+	return nil
+}
+
+func (s *Users) GetUserById(ctx context.Context, id string) (*models.User, error) {
+	return s.getUser(ctx, "id", id)
+}
+
+func (s *Users) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.getUser(ctx, "email", email)
+}
+
+func (s *Users) GetUserByCognitoUserName(ctx context.Context, cognitoUserName string) (*models.User, error) {
+	return s.getUser(ctx, "cognito_user_id", cognitoUserName)
+}
+
+// getUser returns the user that isn't soft deleted with the value in the column
+func (s *Users) getUser(ctx context.Context, column, value string) (*models.User, error) {
+	user := &models.User{}
+	row := s.conn.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE "+column+" = ? AND deleted_at = 0", value)
+	err := row.Scan(&user.Id, &user.Email, &user.CognitoUserName, &user.CreatedAtUnix, &user.UpdatedAtUnix, &user.DeletedAtUnix)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %v", models.ErrUserNotFound, value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute SQL statement: %v", err)
+	}
+	return user, nil
+}
+
+func (s *Users) UpdateUserEmail(ctx context.Context, id, email string) error {
+	result, err := s.conn.ExecContext(ctx, "UPDATE users SET email = ?, updated_at = ? WHERE id = ? AND deleted_at = 0",
+		email, time.Now().Unix(), id)
+	if err != nil {
+		return s.mapError(err, email)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read affected rows: %v", err)
+	}
+	if rowsAffected == 0 {
+		// MySQL doesn't count rows whose values didn't change, so check whether the user exists
+		var exists int
+		err := s.conn.QueryRowContext(ctx, "SELECT 1 FROM users WHERE id = ? AND deleted_at = 0", id).Scan(&exists)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %v", models.ErrUserNotFound, id)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to execute SQL statement: %v", err)
+		}
+	}
+	return nil
+}
+
+// SoftDeleteUser marks the user as deleted while keeping the row, which hides it from the getters.
+// The email and Cognito user name are replaced by a placeholder derived from the id, since their
+// unique indexes would otherwise keep them from being used by a new user.
+func (s *Users) SoftDeleteUser(ctx context.Context, id string) error {
+	now := time.Now().Unix()
+	placeholder := deletedUserPlaceholder + id
+	return s.execAffectingUser(ctx, id, "UPDATE users SET email = ?, cognito_user_id = ?, deleted_at = ?, updated_at = ? WHERE id = ? AND deleted_at = 0",
+		placeholder, placeholder, now, now, id)
+}
+
+// DeleteUser removes the user row, whether or not it was soft deleted
+func (s *Users) DeleteUser(ctx context.Context, id string) error {
+	return s.execAffectingUser(ctx, id, "DELETE FROM users WHERE id = ?", id)
+}
+
+func (s *Users) DeleteUserByEmail(ctx context.Context, email string) error {
+	return s.execAffectingUser(ctx, email, "DELETE FROM users WHERE email = ?", email)
+}
+
+// execAffectingUser runs a statement that must affect exactly one user, identified by key in errors
+func (s *Users) execAffectingUser(ctx context.Context, key, statement string, args ...interface{}) error {
+	result, err := s.conn.ExecContext(ctx, statement, args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute SQL statement: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read affected rows: %v", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %v", models.ErrUserNotFound, key)
+	}
+
+	return nil
+}
+
+// mapError turns unique index violations into ErrUserAlreadyExists
+func (s *Users) mapError(err error, email string) error {
+	if s.isDuplicateEntry(err) {
+		return fmt.Errorf("%w: %v", models.ErrUserAlreadyExists, email)
+	}
+	return fmt.Errorf("failed to execute SQL statement: %v", err)
+}
@@ -1,19 +1,91 @@
 package user
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"score/app/models"
+)
+
 type UserService struct {
-	datastore Datastore
+	datastore      Datastore
+	eventPublisher PlatformEventPublisher
 }
 
-func New(datastore Datastore) *UserService {
+func New(datastore Datastore, eventPublisher PlatformEventPublisher) *UserService {
 	return &UserService{
-		datastore: datastore,
+		datastore:      datastore,
+		eventPublisher: eventPublisher,
 	}
 }
 
 type Datastore interface {
-	CreateUser(email, cognitoUserName string) error
+	CreateUser(ctx context.Context, email, cognitoUserName string) error
+	GetUserById(ctx context.Context, id string) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByCognitoUserName(ctx context.Context, cognitoUserName string) (*models.User, error)
+	UpdateUserEmail(ctx context.Context, id, email string) error
+	SoftDeleteUser(ctx context.Context, id string) error
+	DeleteUser(ctx context.Context, id string) error
+	DeleteUserByEmail(ctx context.Context, email string) error
+	DeleteEmailSubscription(ctx context.Context, email string) error
+}
+
+type PlatformEventPublisher interface {
+	PublishAccountDeletedEvent(ctx context.Context, email string) error
+}
+
+func (s *UserService) CreateUser(ctx context.Context, email, cognitoUserName string) error {
+	return s.datastore.CreateUser(ctx, email, cognitoUserName)
+}
+
+func (s *UserService) GetUserById(ctx context.Context, id string) (*models.User, error) {
+	return s.datastore.GetUserById(ctx, id)
+}
+
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.datastore.GetUserByEmail(ctx, email)
+}
+
+func (s *UserService) GetUserByCognitoUserName(ctx context.Context, cognitoUserName string) (*models.User, error) {
+	return s.datastore.GetUserByCognitoUserName(ctx, cognitoUserName)
 }
 
-func (s *UserService) CreateUser(email, cognitoUserName string) error {
-	return s.datastore.CreateUser(email, cognitoUserName)
+// UpdateUserEmail changes the email of the user, failing with models.ErrUserAlreadyExists when
+// another user already has that email
+func (s *UserService) UpdateUserEmail(ctx context.Context, id, email string) error {
+	return s.datastore.UpdateUserEmail(ctx, id, email)
+}
+
+// SoftDeleteUser hides the user from lookups while keeping its record. The email and Cognito user
+// name are released for new users.
+func (s *UserService) SoftDeleteUser(ctx context.Context, id string) error {
+	return s.datastore.SoftDeleteUser(ctx, id)
+}
+
+// DeleteUser permanently removes the user record
+func (s *UserService) DeleteUser(ctx context.Context, id string) error {
+	return s.datastore.DeleteUser(ctx, id)
+}
+
+// DeleteAccount removes the user with the Cognito user name and the email subscription of their
+// stored email, then notifies downstream services. It fails with models.ErrUserNotFound when there
+// is no such user. The user is deleted last so that a retry after a partial failure still finds it
+// and completes the deletion, at the cost of publishing the event again.
+func (s *UserService) DeleteAccount(ctx context.Context, cognitoUserName string) error {
+	user, err := s.datastore.GetUserByCognitoUserName(ctx, cognitoUserName)
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+	if err := s.datastore.DeleteEmailSubscription(ctx, user.Email); err != nil {
+		return fmt.Errorf("error deleting email subscription: %w", err)
+	}
+	if err := s.eventPublisher.PublishAccountDeletedEvent(ctx, user.Email); err != nil {
+		return fmt.Errorf("error publishing account deleted event: %w", err)
+	}
+	// A concurrent deletion may have removed the user since it was read
+	if err := s.datastore.DeleteUser(ctx, user.Id); err != nil && !errors.Is(err, models.ErrUserNotFound) {
+		return fmt.Errorf("error deleting user: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,168 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"score/app/models"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDatastore keeps users keyed by id and subscriptions keyed by email, and fails the calls named
+// in failures once
+type fakeDatastore struct {
+	users         map[string]models.User
+	subscriptions map[string]bool
+	failures      map[string]error
+}
+
+func newFakeDatastore(users ...models.User) *fakeDatastore {
+	s := &fakeDatastore{
+		users:         map[string]models.User{},
+		subscriptions: map[string]bool{},
+		failures:      map[string]error{},
+	}
+	for _, user := range users {
+		s.users[user.Id] = user
+		s.subscriptions[user.Email] = true
+	}
+	return s
+}
+
+func (s *fakeDatastore) fail(call string) error {
+	err := s.failures[call]
+	delete(s.failures, call)
+	return err
+}
+
+func (s *fakeDatastore) CreateUser(ctx context.Context, email, cognitoUserName string) error {
+	return nil
+}
+
+func (s *fakeDatastore) GetUserById(ctx context.Context, id string) (*models.User, error) {
+	return nil, nil
+}
+
+func (s *fakeDatastore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return nil, nil
+}
+
+func (s *fakeDatastore) GetUserByCognitoUserName(ctx context.Context, cognitoUserName string) (*models.User, error) {
+	for _, user := range s.users {
+		if user.CognitoUserName == cognitoUserName {
+			return &user, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %v", models.ErrUserNotFound, cognitoUserName)
+}
+
+func (s *fakeDatastore) UpdateUserEmail(ctx context.Context, id, email string) error {
+	return nil
+}
+
+func (s *fakeDatastore) SoftDeleteUser(ctx context.Context, id string) error {
+	return nil
+}
+
+func (s *fakeDatastore) DeleteUser(ctx context.Context, id string) error {
+	if err := s.fail("DeleteUser"); err != nil {
+		return err
+	}
+	if _, ok := s.users[id]; !ok {
+		return fmt.Errorf("%w: %v", models.ErrUserNotFound, id)
+	}
+	delete(s.users, id)
+	return nil
+}
+
+func (s *fakeDatastore) DeleteUserByEmail(ctx context.Context, email string) error {
+	return nil
+}
+
+func (s *fakeDatastore) DeleteEmailSubscription(ctx context.Context, email string) error {
+	if err := s.fail("DeleteEmailSubscription"); err != nil {
+		return err
+	}
+	delete(s.subscriptions, email)
+	return nil
+}
+
+// fakePublisher records the emails of the account deleted events it publishes, failing once with err
+type fakePublisher struct {
+	err    error
+	emails []string
+}
+
+func (p *fakePublisher) PublishAccountDeletedEvent(ctx context.Context, email string) error {
+	if err := p.err; err != nil {
+		p.err = nil
+		return err
+	}
+	p.emails = append(p.emails, email)
+	return nil
+}
+
+func TestDeleteAccount(t *testing.T) {
+	ctx := context.Background()
+	user := models.User{Id: "user-1", Email: "a@example.com", CognitoUserName: "cognito-a"}
+	other := models.User{Id: "user-2", Email: "b@example.com", CognitoUserName: "cognito-b"}
+
+	t.Run("happy path", func(t *testing.T) {
+		datastore := newFakeDatastore(user, other)
+		publisher := &fakePublisher{}
+		service := New(datastore, publisher)
+		require.NoError(t, service.DeleteAccount(ctx, user.CognitoUserName), "Deleting an account should succeed")
+		require.NotContains(t, datastore.users, user.Id, "The user should be deleted")
+		require.NotContains(t, datastore.subscriptions, user.Email, "The email subscription of the stored email should be deleted")
+		require.Contains(t, datastore.users, other.Id, "Other users should be kept")
+		require.Contains(t, datastore.subscriptions, other.Email, "Other subscriptions should be kept")
+		require.Equal(t, []string{user.Email}, publisher.emails, "The account deleted event should be published")
+	})
+
+	t.Run("missing user", func(t *testing.T) {
+		datastore := newFakeDatastore(other)
+		publisher := &fakePublisher{}
+		service := New(datastore, publisher)
+		require.ErrorIs(t, service.DeleteAccount(ctx, user.CognitoUserName), models.ErrUserNotFound, "Deleting a missing account should report it")
+		require.Len(t, datastore.subscriptions, 1, "No subscription should be deleted")
+		require.Empty(t, publisher.emails, "No event should be published")
+	})
+
+	t.Run("retry after partial failure", func(t *testing.T) {
+		datastore := newFakeDatastore(user)
+		datastore.failures["DeleteUser"] = errors.New("connection reset")
+		publisher := &fakePublisher{}
+		service := New(datastore, publisher)
+		require.Error(t, service.DeleteAccount(ctx, user.CognitoUserName), "A failed user delete should fail the deletion")
+		require.Contains(t, datastore.users, user.Id, "The user should be kept for the retry")
+
+		require.NoError(t, service.DeleteAccount(ctx, user.CognitoUserName), "Retrying should succeed")
+		require.Empty(t, datastore.users, "The retry should delete the user")
+		require.Empty(t, datastore.subscriptions, "The email subscription should be deleted")
+	})
+
+	t.Run("publish failure", func(t *testing.T) {
+		datastore := newFakeDatastore(user)
+		publisher := &fakePublisher{err: errors.New("topic unavailable")}
+		service := New(datastore, publisher)
+		require.Error(t, service.DeleteAccount(ctx, user.CognitoUserName), "A failed publish should fail the deletion")
+		require.Contains(t, datastore.users, user.Id, "The user should be kept for the retry")
+		require.Empty(t, publisher.emails, "The failed event should not be recorded")
+
+		require.NoError(t, service.DeleteAccount(ctx, user.CognitoUserName), "Retrying should succeed")
+		require.Empty(t, datastore.users, "The retry should delete the user")
+		require.Equal(t, []string{user.Email}, publisher.emails, "The retry should publish the account deleted event")
+	})
+
+	t.Run("subscription delete failure", func(t *testing.T) {
+		datastore := newFakeDatastore(user)
+		datastore.failures["DeleteEmailSubscription"] = errors.New("connection reset")
+		publisher := &fakePublisher{}
+		service := New(datastore, publisher)
+		require.Error(t, service.DeleteAccount(ctx, user.CognitoUserName), "A failed subscription delete should fail the deletion")
+		require.Contains(t, datastore.users, user.Id, "The user should be kept when the subscription delete fails")
+		require.Empty(t, publisher.emails, "No event should be published after a failure")
+	})
+}
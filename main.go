@@ -4,10 +4,15 @@ import (
 	"embed"
 	"flag"
 	"os"
+	"score/app/runners/configdump"
 	"score/app/runners/confirmer"
+	"score/app/runners/doctor"
+	"score/app/runners/export"
+	"score/app/runners/migrate"
 	"score/app/runners/preauth"
 	"score/app/runners/server"
 	"score/app/runners/worker"
+	"strings"
 )
 
 //go:embed dist/*
@@ -16,10 +21,15 @@ var dist embed.FS
 type ExecutionMode string
 
 const (
-	ServerMode    ExecutionMode = "server"
-	WorkerMode    ExecutionMode = "worker"
-	ConfirmerMode ExecutionMode = "confirmer"
-	PreauthMode   ExecutionMode = "preauth"
+	ServerMode     ExecutionMode = "server"
+	WorkerMode     ExecutionMode = "worker"
+	WorkerPollMode ExecutionMode = "worker-poll"
+	ConfirmerMode  ExecutionMode = "confirmer"
+	PreauthMode    ExecutionMode = "preauth"
+	ConfigMode     ExecutionMode = "config"
+	DoctorMode     ExecutionMode = "doctor"
+	MigrateMode    ExecutionMode = "migrate"
+	ExportMode     ExecutionMode = "export"
 )
 
 func main() {
@@ -37,10 +47,20 @@ func RunApp(mode ExecutionMode) error {
 		err = server.Run(dist)
 	case WorkerMode:
 		err = worker.Run()
+	case WorkerPollMode:
+		err = worker.RunPoller()
 	case ConfirmerMode:
 		err = confirmer.Run()
 	case PreauthMode:
 		err = preauth.Run()
+	case ConfigMode:
+		err = configdump.Run()
+	case DoctorMode:
+		err = doctor.Run()
+	case MigrateMode:
+		err = migrate.Run(getMigrateCommand())
+	case ExportMode:
+		err = export.Run(getExportOptions())
 	default:
 		err = server.Run(dist)
 	}
@@ -48,11 +68,42 @@ func RunApp(mode ExecutionMode) error {
 }
 
 // Checks the 'SCORE_MODE' environment variable to determine the execution mode. If it is not set, it will check the 'mode' command line option. If that is not set, it will default to 'server' mode.
+// The command line is parsed either way since some modes read their arguments from it.
 func getExecutionMode() ExecutionMode {
+	executionModePtr := flag.String("mode", "server", "The execution mode of the application.")
+	flag.Parse()
 	if os.Getenv("SCORE_MODE") != "" {
 		return ExecutionMode(os.Getenv("SCORE_MODE"))
 	}
-	executionModePtr := flag.String("mode", "server", "The execution mode of the application.")
-	flag.Parse()
 	return ExecutionMode(*executionModePtr)
 }
+
+// Checks the 'SCORE_MIGRATE_COMMAND' environment variable to determine the migrate command (up, down, status or baseline). If it is not set, it will use the first command line argument after the flags. If that is not set, it will default to 'status'.
+func getMigrateCommand() string {
+	if os.Getenv("SCORE_MIGRATE_COMMAND") != "" {
+		return os.Getenv("SCORE_MIGRATE_COMMAND")
+	}
+	if flag.Arg(0) != "" {
+		return flag.Arg(0)
+	}
+	return migrate.StatusCommand
+}
+
+// Checks the 'SCORE_EXPORT_FORMAT' (csv or jsonl) and 'SCORE_EXPORT_FILTERS' (comma separated name=value pairs) environment variables to determine the export options. If they are not set, the first command line argument after the flags is the format and the following ones are the filters. The format defaults to 'csv'.
+func getExportOptions() (string, []string) {
+	format := os.Getenv("SCORE_EXPORT_FORMAT")
+	filters := []string{}
+	if os.Getenv("SCORE_EXPORT_FILTERS") != "" {
+		filters = strings.Split(os.Getenv("SCORE_EXPORT_FILTERS"), ",")
+	}
+	if format == "" && flag.Arg(0) != "" {
+		format = flag.Arg(0)
+		if len(filters) == 0 {
+			filters = flag.Args()[1:]
+		}
+	}
+	if format == "" {
+		format = export.DefaultFormat
+	}
+	return format, filters
+}
@@ -5,7 +5,11 @@ import (
 	"errors"
 	"flag"
 	"os"
+	"score/app/runners/configdump"
 	"score/app/runners/confirmer"
+	"score/app/runners/doctor"
+	"score/app/runners/export"
+	"score/app/runners/migrate"
 	"score/app/runners/preauth"
 	"score/app/runners/server"
 	"score/app/runners/worker"
@@ -23,6 +27,10 @@ var mockRunWorker = func() error {
 	return nil
 }
 
+var mockRunWorkerPoller = func() error {
+	return nil
+}
+
 var mockRunPostAccountConfirmationHandler = func() error {
 	return nil
 }
@@ -31,17 +39,40 @@ var mockRunPreAuthenticationHandler = func() error {
 	return nil
 }
 
+var mockRunConfigDump = func() error {
+	return nil
+}
+
+var mockRunDoctor = func() error {
+	return nil
+}
+
+var mockRunMigrate = func(string) error {
+	return nil
+}
+
+var mockRunExport = func(string, []string) error {
+	return nil
+}
+
 func TestMain(m *testing.M) {
 	server.Run = mockRunServer
 	worker.Run = mockRunWorker
+	worker.RunPoller = mockRunWorkerPoller
 	confirmer.Run = mockRunPostAccountConfirmationHandler
 	preauth.Run = mockRunPreAuthenticationHandler
+	configdump.Run = mockRunConfigDump
+	doctor.Run = mockRunDoctor
+	migrate.Run = mockRunMigrate
+	export.Run = mockRunExport
 
 	os.Exit(m.Run())
 }
 
 func TestGetExecutionMode(t *testing.T) {
 	// Test the environment variable
+	os.Args = []string{"cmd"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
 	os.Setenv("SCORE_MODE", "worker")
 	executionMode := getExecutionMode()
 	require.Equal(t, ExecutionMode("worker"), executionMode, "Execution mode should be worker")
@@ -65,6 +96,36 @@ func TestGetExecutionMode(t *testing.T) {
 	require.Equal(t, ExecutionMode("server"), executionMode, "Execution mode should be server by default")
 }
 
+func TestGetMigrateCommandWithEnvironmentMode(t *testing.T) {
+	// Reset the flags after the test
+	defer func() {
+		os.Args = []string{"cmd"}
+		flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	}()
+	t.Setenv("SCORE_MODE", "migrate")
+	os.Args = []string{"cmd", "up"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+	require.Equal(t, MigrateMode, getExecutionMode(), "Execution mode should be migrate")
+	require.Equal(t, "up", getMigrateCommand(), "Migrate command should be read from the command line")
+}
+
+func TestGetExportOptionsWithEnvironmentMode(t *testing.T) {
+	// Reset the flags after the test
+	defer func() {
+		os.Args = []string{"cmd"}
+		flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	}()
+	t.Setenv("SCORE_MODE", "export")
+	os.Args = []string{"cmd", "jsonl", "verified=true", "bounced=false"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+	require.Equal(t, ExportMode, getExecutionMode(), "Execution mode should be export")
+	format, filters := getExportOptions()
+	require.Equal(t, "jsonl", format, "Export format should be read from the command line")
+	require.Equal(t, []string{"verified=true", "bounced=false"}, filters, "Export filters should be read from the command line")
+}
+
 func TestRunApp(t *testing.T) {
 	t.Run("server", func(t *testing.T) {
 		originalRun := server.Run
@@ -87,6 +148,16 @@ func TestRunApp(t *testing.T) {
 		require.NoError(t, err, "Worker mode should not return an error")
 	})
 
+	t.Run("worker-poll", func(t *testing.T) {
+		originalRun := worker.RunPoller
+		worker.RunPoller = func() error {
+			return nil
+		}
+		defer func() { worker.RunPoller = originalRun }()
+		err := RunApp(WorkerPollMode)
+		require.NoError(t, err, "Worker poll mode should not return an error")
+	})
+
 	t.Run("confirmer", func(t *testing.T) {
 		originalRun := confirmer.Run
 		confirmer.Run = func() error {
@@ -107,6 +178,57 @@ func TestRunApp(t *testing.T) {
 		require.NoError(t, err, "Preauth mode should not return an error")
 	})
 
+	t.Run("config", func(t *testing.T) {
+		originalRun := configdump.Run
+		configdump.Run = func() error {
+			return nil
+		}
+		defer func() { configdump.Run = originalRun }()
+		err := RunApp(ConfigMode)
+		require.NoError(t, err, "Config mode should not return an error")
+	})
+
+	t.Run("doctor", func(t *testing.T) {
+		originalRun := doctor.Run
+		doctor.Run = func() error {
+			return nil
+		}
+		defer func() { doctor.Run = originalRun }()
+		err := RunApp(DoctorMode)
+		require.NoError(t, err, "Doctor mode should not return an error")
+	})
+
+	t.Run("migrate", func(t *testing.T) {
+		originalRun := migrate.Run
+		var command string
+		migrate.Run = func(c string) error {
+			command = c
+			return nil
+		}
+		defer func() { migrate.Run = originalRun }()
+		t.Setenv("SCORE_MIGRATE_COMMAND", "up")
+		err := RunApp(MigrateMode)
+		require.NoError(t, err, "Migrate mode should not return an error")
+		require.Equal(t, "up", command, "Migrate command should be read from the environment")
+	})
+
+	t.Run("export", func(t *testing.T) {
+		originalRun := export.Run
+		var format string
+		var filters []string
+		export.Run = func(f string, fs []string) error {
+			format, filters = f, fs
+			return nil
+		}
+		defer func() { export.Run = originalRun }()
+		t.Setenv("SCORE_EXPORT_FORMAT", "jsonl")
+		t.Setenv("SCORE_EXPORT_FILTERS", "verified=true,bounced=false")
+		err := RunApp(ExportMode)
+		require.NoError(t, err, "Export mode should not return an error")
+		require.Equal(t, "jsonl", format, "Export format should be read from the environment")
+		require.Equal(t, []string{"verified=true", "bounced=false"}, filters, "Export filters should be read from the environment")
+	})
+
 	t.Run("default", func(t *testing.T) {
 		originalRun := server.Run
 		server.Run = func(fs embed.FS) error {